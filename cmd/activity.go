@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// maxRecent is how many actions the menu shows
+const maxRecent = 5
+
+// activity is one menu operation and how it ended
+type activity struct {
+	action string
+	result string
+}
+
+// recentActions is a ring buffer of the last maxRecent activities
+type recentActions struct {
+	items [maxRecent]activity
+	// next is where the next activity goes, n how many are stored
+	next int
+	n    int
+}
+
+func (r *recentActions) record(action, result string) {
+	r.items[r.next] = activity{action: action, result: result}
+	r.next = (r.next + 1) % maxRecent
+	if r.n < maxRecent {
+		r.n++
+	}
+}
+
+// list returns the stored activities oldest first
+func (r *recentActions) list() []activity {
+	out := make([]activity, 0, r.n)
+	start := (r.next - r.n + maxRecent) % maxRecent
+	for i := 0; i < r.n; i++ {
+		out = append(out, r.items[(start+i)%maxRecent])
+	}
+	return out
+}
+
+// print writes the recent actions, nothing before the first one
+func (r *recentActions) print(w io.Writer) {
+	if r.n == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\nRecent actions:")
+	for _, a := range r.list() {
+		fmt.Fprintf(w, "  %s : %s\n", a.action, a.result)
+	}
+}
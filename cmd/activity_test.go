@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Recent actions buffer test
+func TestRecentActions(t *testing.T) {
+	var r recentActions
+	for i := 1; i <= 7; i++ {
+		r.record("create", fmt.Sprintf("created u%d", i))
+	}
+
+	got := r.list()
+	if len(got) != maxRecent {
+		t.Fatalf("Expected %d actions, got %d", maxRecent, len(got))
+	}
+	for i, a := range got {
+		if want := fmt.Sprintf("created u%d", i+3); a.result != want {
+			t.Errorf("Expected %s at %d, got %s", want, i, a.result)
+		}
+	}
+
+	out := &bytes.Buffer{}
+	r.print(out)
+	if strings.Contains(out.String(), "u2") || !strings.Contains(out.String(), "create : created u7") {
+		t.Errorf("Unexpected output %q", out.String())
+	}
+}
+
+// Recent actions in the menu test
+func TestMenuShowsRecentActions(t *testing.T) {
+	c, out := newTestCli(newStore(t), "1\nalice\nalice@test.com\n2\n", time.Second)
+	c.run(context.Background())
+
+	if !strings.Contains(out.String(), "Recent actions:\n  create : created alice (id 1)\n  list : 1 users") {
+		t.Errorf("Expected recent actions in the menu, got %q", out.String())
+	}
+}
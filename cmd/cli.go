@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dotenv213/umm/internal/userstore"
+)
+
+// cli holds what every menu operation needs
+type cli struct {
+	store   userstore.Store
+	scanner *bufio.Scanner
+	out     io.Writer
+	timeout time.Duration
+	// ctx is cancelled on SIGINT, every operation derives from it
+	ctx context.Context
+	// lines is fed by the goroutine reading scanner
+	lines chan string
+	// recent is shown above the menu
+	recent recentActions
+	// log gets errors and diagnostics on stderr, see newLogger
+	log *slog.Logger
+	// errOut gets flag usage and parse errors, stderr in main
+	errOut io.Writer
+}
+
+// run shows the menu until the user exits, input ends or ctx is cancelled
+func (c *cli) run(ctx context.Context) {
+	c.ctx = ctx
+	for {
+		c.recent.print(c.out)
+		fmt.Fprintln(c.out, "\n--- User Management System ---")
+		fmt.Fprintln(c.out, "1. Create User")
+		fmt.Fprintln(c.out, "2. List All Users")
+		fmt.Fprintln(c.out, "3. Update User")
+		fmt.Fprintln(c.out, "4. Delete User")
+		fmt.Fprintln(c.out, "5. Exit")
+		fmt.Fprintln(c.out, "Select an option: ")
+
+		choice, ok := c.nextLine()
+		if !ok {
+			// a cancel is reported by main
+			if c.baseContext().Err() == nil {
+				fmt.Fprintln(c.out, "Input ended, exiting...")
+			}
+			return
+		}
+		switch choice {
+		case "1":
+			c.recent.record("create", c.createUser())
+		case "2":
+			c.recent.record("list", c.listUsers())
+		case "3":
+			c.recent.record("update", c.updateUser())
+		case "4":
+			c.recent.record("delete", c.deleteUser())
+		case "5":
+			fmt.Fprintln(c.out, "Exiting program...")
+			return
+		}
+	}
+}
+
+// readLine prompts for one line, ok is false once the input ended
+// the operation then stops and run exits on its next read
+func (c *cli) readLine(prompt string) (string, bool) {
+	fmt.Fprint(c.out, prompt)
+	return c.nextLine()
+}
+
+// inputEnded is the result of an operation cut short by the end of input
+const inputEnded = "input ended"
+
+// nextLine waits for the next input line
+// ok is false once the input ended or the run was cancelled
+// reading happens in a goroutine so a cancel does not wait for enter
+func (c *cli) nextLine() (string, bool) {
+	if c.lines == nil {
+		c.lines = make(chan string)
+		go func() {
+			for c.scanner.Scan() {
+				c.lines <- c.scanner.Text()
+			}
+			close(c.lines)
+		}()
+	}
+	select {
+	case <-c.baseContext().Done():
+		return "", false
+	case line, ok := <-c.lines:
+		return strings.TrimSpace(line), ok
+	}
+}
+
+// baseContext is the run context, Background until run or main sets one
+func (c *cli) baseContext() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// opContext bounds a single store call so a hung database
+// can not block the program forever
+func (c *cli) opContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(c.baseContext(), c.timeout)
+}
+
+// failed logs msg with a friendly message for err as an error and err itself at debug
+// and returns the short result for the recent actions
+func (c *cli) failed(msg string, err error) string {
+	c.logger().Debug(msg, "err", err)
+	text := userMessage(err)
+	c.logger().Error(msg, "err", text)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "timed out"
+	}
+	if errors.Is(err, context.Canceled) {
+		return "cancelled"
+	}
+	return "failed : " + text
+}
+
+// the menu operations return a short result for the recent actions
+
+func (c *cli) createUser() string {
+	uname, ok := c.readLine("Enter Username: ")
+	if !ok {
+		return inputEnded
+	}
+	email, ok := c.readLine("Enter Email: ")
+	if !ok {
+		return inputEnded
+	}
+	if uname == "" || email == "" {
+		c.logger().Error("username and email are required")
+		return "missing username or email"
+	}
+	u := &userstore.User{Username: uname, Email: email}
+
+	ctx, cancel := c.opContext()
+	defer cancel()
+	if err := c.store.Create(ctx, u); err != nil {
+		return c.failed("failed to create user", err)
+	}
+	fmt.Fprintln(c.out, "User Created!")
+	return fmt.Sprintf("created %s (id %d)", u.Username, u.ID)
+}
+
+func (c *cli) listUsers() string {
+	ctx, cancel := c.opContext()
+	defer cancel()
+	users, err := c.store.ListAll(ctx)
+	if err != nil {
+		return c.failed("failed to list users", err)
+	}
+	formatUsers(c.out, users, formatTable)
+	return fmt.Sprintf("%d users", len(users))
+}
+
+// getUser reads an id and loads the user, logging why when it can not
+// result says why when ok is false
+func (c *cli) getUser(prompt, invalidMsg string) (u *userstore.User, result string, ok bool) {
+	idStr, ok := c.readLine(prompt)
+	if !ok {
+		return nil, inputEnded, false
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.logger().Error(invalidMsg)
+		return nil, "invalid id", false
+	}
+
+	ctx, cancel := c.opContext()
+	defer cancel()
+	u, err = c.store.GetById(ctx, id)
+	if err != nil {
+		if errors.Is(err, userstore.ErrUserNotFound) {
+			c.logger().Error("User not found")
+			return nil, "user not found", false
+		}
+		return nil, c.failed("failed to get user", err), false
+	}
+	return u, "", true
+}
+
+func (c *cli) updateUser() string {
+	u, result, ok := c.getUser("Enter user ID: ", "invalid id")
+	if !ok {
+		return result
+	}
+	newU, ok := c.readLine(fmt.Sprintf("Username [%s]: ", u.Username))
+	if !ok {
+		return inputEnded
+	}
+	if newU != "" {
+		u.Username = newU
+	}
+
+	newE, ok := c.readLine(fmt.Sprintf("Email [%s]: ", u.Email))
+	if !ok {
+		return inputEnded
+	}
+	if newE != "" {
+		u.Email = newE
+	}
+
+	ctx, cancel := c.opContext()
+	defer cancel()
+	if err := c.store.Update(ctx, u); err != nil {
+		return c.failed("Update failed", err)
+	}
+	fmt.Fprintln(c.out, "Updated successfully!")
+	return fmt.Sprintf("updated id %d", u.ID)
+}
+
+func (c *cli) deleteUser() string {
+	u, result, ok := c.getUser("Enter a user ID to delete: ", "Invalid ID format")
+	if !ok {
+		return result
+	}
+
+	confirm, ok := c.readLine("Are you sure you want to delete? (y/n): ")
+	if !ok {
+		return inputEnded
+	}
+	if confirm != "y" {
+		return "not confirmed"
+	}
+
+	ctx, cancel := c.opContext()
+	defer cancel()
+	if err := c.store.Delete(ctx, u.ID); err != nil {
+		return c.failed("Delete failed", err)
+	}
+	fmt.Fprintln(c.out, "User deleted successfuly")
+	return fmt.Sprintf("deleted id %d", u.ID)
+}
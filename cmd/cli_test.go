@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dotenv213/umm/internal/userstore"
+)
+
+// slowStore blocks until the context gives up
+type slowStore struct {
+	userstore.Store
+}
+
+func (s *slowStore) Create(ctx context.Context, user *userstore.User) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func newStore(t *testing.T) userstore.Store {
+	t.Helper()
+	store, err := userstore.NewDb(":memory:")
+	if err != nil {
+		t.Fatalf("Create DB: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+	return store
+}
+
+func newTestCli(store userstore.Store, input string, timeout time.Duration) (*cli, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	return &cli{
+		store:   store,
+		scanner: bufio.NewScanner(strings.NewReader(input)),
+		out:     out,
+		timeout: timeout,
+	}, out
+}
+
+// Create with timeout test
+func TestCreateTimeout(t *testing.T) {
+	c, _ := newTestCli(&slowStore{}, "alice\nalice@test.com\n", 10*time.Millisecond)
+	var logs bytes.Buffer
+	c.log = newLogger(&logs, false)
+
+	c.createUser()
+
+	if !strings.Contains(logs.String(), "operation timed out") {
+		t.Errorf("Expected timeout message, got %q", logs.String())
+	}
+}
+
+// Create success test
+func TestCreateUserCommand(t *testing.T) {
+	c, out := newTestCli(newStore(t), "alice\nalice@test.com\n", time.Second)
+	c.createUser()
+
+	if !strings.Contains(out.String(), "User Created!") {
+		t.Errorf("Expected success message, got %q", out.String())
+	}
+}
+
+// Menu loop returns on cancel test
+func TestRunCancelled(t *testing.T) {
+	// the pipe never delivers a line, like a user that does not type
+	r, w := io.Pipe()
+	defer w.Close()
+	c := &cli{store: newStore(t), scanner: bufio.NewScanner(r), out: &bytes.Buffer{}, timeout: time.Second}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.run(ctx)
+		close(done)
+	}()
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected run to return after cancel")
+	}
+}
+
+// Menu loop exit option test
+func TestRunExit(t *testing.T) {
+	c, out := newTestCli(newStore(t), "2\n5\n", time.Second)
+	c.run(context.Background())
+	if !strings.Contains(out.String(), "Exiting program...") {
+		t.Errorf("Expected exit message, got %q", out.String())
+	}
+}
+
+// Menu loop ends with the input test
+func TestRunInputEnds(t *testing.T) {
+	for _, input := range []string{"", "2\n", "1\nalice\n", "4\n"} {
+		store := newStore(t)
+		c, out := newTestCli(store, input, time.Second)
+		done := make(chan struct{})
+		go func() {
+			c.run(context.Background())
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("Expected run to return when input %q ends", input)
+		}
+		if !strings.Contains(out.String(), "Input ended, exiting...") {
+			t.Errorf("Expected the end of input message for %q, got %q", input, out.String())
+		}
+		if n, _ := store.Count(context.Background()); n != 0 {
+			t.Errorf("Expected no user from a cut short create, got %d", n)
+		}
+	}
+}
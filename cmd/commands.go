@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runCommand runs a one-shot subcommand such as list, export or import
+func (c *cli) runCommand(args []string) error {
+	switch args[0] {
+	case "list":
+		fs := flag.NewFlagSet("list", flag.ContinueOnError)
+		fs.SetOutput(c.stderr())
+		format := fs.String("format", "table", "output format: table, tsv or csv")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		f, err := parseListFormat(*format)
+		if err != nil {
+			return err
+		}
+		return c.printUsers(f)
+	case "export":
+		fs := flag.NewFlagSet("export", flag.ContinueOnError)
+		fs.SetOutput(c.stderr())
+		file := fs.String("file", "users.csv", "csv file to write")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		return c.exportUsers(*file)
+	case "import":
+		fs := flag.NewFlagSet("import", flag.ContinueOnError)
+		fs.SetOutput(c.stderr())
+		file := fs.String("file", "users.csv", "csv file to read")
+		skip := fs.Bool("skip-duplicates", false, "skip users that already exist")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		return c.importUsers(*file, *skip)
+	}
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+func (c *cli) printUsers(f listFormat) error {
+	ctx, cancel := c.opContext()
+	defer cancel()
+	users, err := c.store.ListAll(ctx)
+	if err != nil {
+		return c.commandError("list failed", err)
+	}
+	return formatUsers(c.out, users, f)
+}
+
+func (c *cli) exportUsers(path string) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer func() {
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to close %s: %w", path, cerr)
+		}
+	}()
+
+	ctx, cancel := c.opContext()
+	defer cancel()
+	n, err := c.store.ExportCSV(ctx, f)
+	if err != nil {
+		return c.commandError("export failed", err)
+	}
+	fmt.Fprintf(c.out, "%d exported\n", n)
+	return nil
+}
+
+func (c *cli) importUsers(path string, skipDuplicates bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	ctx, cancel := c.opContext()
+	defer cancel()
+	imported, skipped, err := c.store.ImportCSV(ctx, f, skipDuplicates)
+	if err != nil {
+		return c.commandError("import failed", err)
+	}
+	fmt.Fprintf(c.out, "%d imported, %d skipped\n", imported, skipped)
+	return nil
+}
+
+// commandError turns err into the same friendly message the menu uses
+// the full error is logged at debug
+func (c *cli) commandError(msg string, err error) error {
+	c.logger().Debug(msg, "err", err)
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return errors.New(userMessage(err))
+	}
+	return fmt.Errorf("%s: %s", msg, userMessage(err))
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dotenv213/umm/internal/userstore"
+)
+
+// Export then import test
+func TestExportImportCommands(t *testing.T) {
+	ctx := context.Background()
+	src := newStore(t)
+	_ = src.Create(ctx, &userstore.User{Username: "a", Email: "a@test.com"})
+	_ = src.Create(ctx, &userstore.User{Username: "b", Email: "b@test.com"})
+
+	file := filepath.Join(t.TempDir(), "users.csv")
+
+	c, out := newTestCli(src, "", time.Second)
+	if err := c.runCommand([]string{"export", "--file", file}); err != nil {
+		t.Fatalf("export failed : %v", err)
+	}
+	if !strings.Contains(out.String(), "2 exported") {
+		t.Errorf("Unexpected export summary %q", out.String())
+	}
+
+	dst := newStore(t)
+	c, out = newTestCli(dst, "", time.Second)
+	if err := c.runCommand([]string{"import", "--file", file, "--skip-duplicates"}); err != nil {
+		t.Fatalf("import failed : %v", err)
+	}
+	if !strings.Contains(out.String(), "2 imported, 0 skipped") {
+		t.Errorf("Unexpected import summary %q", out.String())
+	}
+
+	want, _ := src.Count(ctx)
+	got, _ := dst.Count(ctx)
+	if got != want {
+		t.Errorf("Expected %d users after import, got %d", want, got)
+	}
+}
+
+// Import from missing file test
+func TestImportMissingFile(t *testing.T) {
+	c, _ := newTestCli(newStore(t), "", time.Second)
+
+	err := c.runCommand([]string{"import", "--file", filepath.Join(t.TempDir(), "nope.csv")})
+	if err == nil || !strings.Contains(err.Error(), "failed to open") {
+		t.Fatalf("Expected open error, got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dotenv213/umm/internal/userstore"
+)
+
+// appConfig is everything the cli needs before it opens the store
+type appConfig struct {
+	dbPath      string
+	busyTimeout time.Duration
+	readOnly    bool
+	timeout     time.Duration
+	// verbose logs debug records, like full store errors
+	verbose bool
+	// args left after the flags, the subcommand if any
+	args []string
+}
+
+// loadConfig resolves the settings with flags over environment over defaults
+// UMM_DB_PATH, UMM_BUSY_TIMEOUT and UMM_READONLY only replace the defaults
+// so a flag given on the command line always wins
+func loadConfig(args []string, getenv func(string) string) (appConfig, error) {
+	cfg := appConfig{
+		dbPath:      "users.db",
+		busyTimeout: 5 * time.Second,
+		timeout:     10 * time.Second,
+	}
+	if v := getenv("UMM_DB_PATH"); v != "" {
+		cfg.dbPath = v
+	}
+	if v := getenv("UMM_BUSY_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid UMM_BUSY_TIMEOUT : %w", err)
+		}
+		cfg.busyTimeout = d
+	}
+	if v := getenv("UMM_READONLY"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid UMM_READONLY : %w", err)
+		}
+		cfg.readOnly = b
+	}
+
+	fs := flag.NewFlagSet("umm", flag.ContinueOnError)
+	fs.StringVar(&cfg.dbPath, "db", cfg.dbPath, "database file (env UMM_DB_PATH)")
+	fs.DurationVar(&cfg.busyTimeout, "busy-timeout", cfg.busyTimeout, "how long to wait for a locked database (env UMM_BUSY_TIMEOUT)")
+	fs.BoolVar(&cfg.readOnly, "readonly", cfg.readOnly, "open the database read-only (env UMM_READONLY)")
+	fs.DurationVar(&cfg.timeout, "timeout", cfg.timeout, "timeout for each database operation")
+	fs.BoolVar(&cfg.verbose, "v", false, "log debug details to stderr")
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+	cfg.args = fs.Args()
+	return cfg, nil
+}
+
+// storeOptions turns the config into options for userstore.NewDb
+func (cfg appConfig) storeOptions() []userstore.Option {
+	return []userstore.Option{
+		userstore.WithBusyTimeout(cfg.busyTimeout),
+		userstore.WithReadOnly(cfg.readOnly),
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeEnv is a getenv backed by a map
+func fakeEnv(vars map[string]string) func(string) string {
+	return func(key string) string {
+		return vars[key]
+	}
+}
+
+// Config from environment test
+func TestLoadConfigFromEnv(t *testing.T) {
+	env := fakeEnv(map[string]string{
+		"UMM_DB_PATH":      "/tmp/env.db",
+		"UMM_BUSY_TIMEOUT": "2s",
+		"UMM_READONLY":     "true",
+	})
+
+	cfg, err := loadConfig([]string{"export", "--file", "out.csv"}, env)
+	if err != nil {
+		t.Fatalf("loadConfig failed : %v", err)
+	}
+	if cfg.dbPath != "/tmp/env.db" || cfg.busyTimeout != 2*time.Second || !cfg.readOnly {
+		t.Errorf("Unexpected config %+v", cfg)
+	}
+	if cfg.timeout != 10*time.Second {
+		t.Errorf("Expected default timeout, got %v", cfg.timeout)
+	}
+	if len(cfg.args) != 3 || cfg.args[0] != "export" {
+		t.Errorf("Expected the subcommand args, got %v", cfg.args)
+	}
+}
+
+// Flag overrides environment test
+func TestLoadConfigFlagOverridesEnv(t *testing.T) {
+	env := fakeEnv(map[string]string{
+		"UMM_DB_PATH":  "/tmp/env.db",
+		"UMM_READONLY": "true",
+	})
+
+	cfg, err := loadConfig([]string{"-db", "flag.db", "-readonly=false"}, env)
+	if err != nil {
+		t.Fatalf("loadConfig failed : %v", err)
+	}
+	if cfg.dbPath != "flag.db" || cfg.readOnly {
+		t.Errorf("Expected flags to win, got %+v", cfg)
+	}
+	if cfg.busyTimeout != 5*time.Second {
+		t.Errorf("Expected default busy timeout, got %v", cfg.busyTimeout)
+	}
+}
+
+// Invalid environment value test
+func TestLoadConfigBadEnv(t *testing.T) {
+	_, err := loadConfig(nil, fakeEnv(map[string]string{"UMM_BUSY_TIMEOUT": "soon"}))
+	if err == nil {
+		t.Fatal("Expected an error for a bad duration")
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dotenv213/umm/internal/userstore"
+)
+
+// listFormat is how users are printed by the menu and umm list
+type listFormat int
+
+const (
+	// formatTable is the aligned table meant for people
+	formatTable listFormat = iota
+	// formatTSV and formatCSV are one header line and plain rows for other tools
+	formatTSV
+	formatCSV
+)
+
+var listFormats = map[string]listFormat{
+	"table": formatTable,
+	"tsv":   formatTSV,
+	"csv":   formatCSV,
+}
+
+func parseListFormat(s string) (listFormat, error) {
+	f, ok := listFormats[strings.ToLower(s)]
+	if !ok {
+		return 0, fmt.Errorf("unknown format %q, use table, tsv or csv", s)
+	}
+	return f, nil
+}
+
+var listHeader = []string{"id", "username", "email", "created_at"}
+
+// tsvCleaner keeps a value on one line and in one column
+var tsvCleaner = strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+
+// formatUsers writes users to w in format f
+func formatUsers(w io.Writer, users []userstore.User, f listFormat) error {
+	switch f {
+	case formatTSV:
+		if _, err := fmt.Fprintln(w, strings.Join(listHeader, "\t")); err != nil {
+			return err
+		}
+		for _, u := range users {
+			row := userRow(u)
+			for i := range row {
+				row[i] = tsvCleaner.Replace(row[i])
+			}
+			if _, err := fmt.Fprintln(w, strings.Join(row, "\t")); err != nil {
+				return err
+			}
+		}
+		return nil
+	case formatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(listHeader); err != nil {
+			return err
+		}
+		for _, u := range users {
+			if err := cw.Write(userRow(u)); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	fmt.Fprintln(w, "\n  ID  |  Name  |  Username  |  Email  | Created at  ")
+	for _, u := range users {
+		if _, err := fmt.Fprintf(w, "%-3d  |  %s  |  %-10s  |  %s  |  %v  \n", u.ID, u.Name(), u.Username, u.Email, u.CreatedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func userRow(u userstore.User) []string {
+	return []string{strconv.FormatInt(u.ID, 10), u.Username, u.Email, u.CreatedAt.Format(time.RFC3339)}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dotenv213/umm/internal/userstore"
+)
+
+// Tab separated list test
+func TestFormatUsersTSV(t *testing.T) {
+	users := []userstore.User{
+		{ID: 1, Username: "a", Email: "a@test.com", CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: 2, Username: "tab\there", Email: "b@test.com"},
+	}
+	var out bytes.Buffer
+	if err := formatUsers(&out, users, formatTSV); err != nil {
+		t.Fatalf("formatUsers failed : %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected header and 2 rows, got %q", out.String())
+	}
+	for _, line := range lines {
+		if cols := strings.Split(line, "\t"); len(cols) != 4 {
+			t.Errorf("Expected 4 tab separated columns, got %q", line)
+		}
+	}
+	if lines[1] != "1\ta\ta@test.com\t2024-01-01T00:00:00Z" {
+		t.Errorf("Unexpected row %q", lines[1])
+	}
+}
+
+// List command format flag test
+func TestListCommand(t *testing.T) {
+	store := newStore(t)
+	_ = store.Create(context.Background(), &userstore.User{Username: "a", Email: "a@test.com"})
+
+	c, out := newTestCli(store, "", time.Second)
+	if err := c.runCommand([]string{"list", "--format", "csv"}); err != nil {
+		t.Fatalf("list failed : %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "id,username,email,created_at\n1,a,a@test.com,") {
+		t.Errorf("Unexpected csv output %q", out.String())
+	}
+
+	if err := c.runCommand([]string{"list", "--format", "xml"}); err == nil {
+		t.Error("Expected an unknown format to fail")
+	}
+}
+
+// Table shows the display name test
+func TestFormatUsersTableName(t *testing.T) {
+	name := "Ada Lovelace"
+	users := []userstore.User{
+		{ID: 1, Username: "ada", Email: "ada@test.com", DisplayName: &name},
+		{ID: 2, Username: "bob", Email: "bob@test.com"},
+	}
+	var out bytes.Buffer
+	if err := formatUsers(&out, users, formatTable); err != nil {
+		t.Fatalf("formatUsers failed : %v", err)
+	}
+	if !strings.Contains(out.String(), "|  Ada Lovelace  |  ada") {
+		t.Errorf("Expected the display name, got %q", out.String())
+	}
+	if !strings.Contains(out.String(), "|  bob  |  bob") {
+		t.Errorf("Expected the username without a display name, got %q", out.String())
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+
+	"github.com/dotenv213/umm/internal/userstore"
+)
+
+// newLogger writes text records to w, usually stderr
+// debug records, like the full store errors, only show with -v
+// the time is left out since the cli is run by people
+func newLogger(w io.Writer, verbose bool) *slog.Logger {
+	level := slog.LevelInfo
+	if verbose {
+		level = slog.LevelDebug
+	}
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
+
+// logger is the cli logger, a discarding one until main sets it
+func (c *cli) logger() *slog.Logger {
+	if c.log == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+	return c.log
+}
+
+// stderr is where flag usage and parse errors go, discarded until main sets it
+func (c *cli) stderr() io.Writer {
+	if c.errOut == nil {
+		return io.Discard
+	}
+	return c.errOut
+}
+
+// userErrors are store errors that tell the user what to fix
+// they are shown as they are, with any context the store added
+var userErrors = []error{
+	userstore.ErrUserNotFound,
+	userstore.ErrDuplicateUser,
+	userstore.ErrReadOnly,
+	userstore.ErrInvalidEmail,
+	userstore.ErrEmailRequired,
+	userstore.ErrEmailDomainNotAllowed,
+	userstore.ErrInvalidCharacter,
+	userstore.ErrInvalidRole,
+	userstore.ErrRateLimited,
+	userstore.ErrInvalidCSV,
+}
+
+// userMessage is what the user sees for err
+// anything else is an internal error, its detail is logged at debug by the caller
+func userMessage(err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "operation timed out"
+	case errors.Is(err, context.Canceled):
+		return "operation cancelled"
+	}
+	for _, target := range userErrors {
+		if errors.Is(err, target) {
+			return err.Error()
+		}
+	}
+	return "unexpected error, run with -v for details"
+}
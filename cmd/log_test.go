@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dotenv213/umm/internal/userstore"
+)
+
+// failingStore fails every Create with err
+type failingStore struct {
+	userstore.Store
+	err error
+}
+
+func (s *failingStore) Create(ctx context.Context, user *userstore.User) error {
+	return s.err
+}
+
+// Friendly message and debug detail test
+func TestFailedLogsDetail(t *testing.T) {
+	internal := fmt.Errorf("failed to insert user : %w", errors.New("disk I/O error"))
+	c, out := newTestCli(&failingStore{err: internal}, "alice\nalice@test.com\n", time.Second)
+	var logs bytes.Buffer
+	c.log = newLogger(&logs, true)
+
+	c.createUser()
+	if !strings.Contains(logs.String(), `level=ERROR msg="failed to create user" err="unexpected error, run with -v for details"`) {
+		t.Errorf("Expected a friendly error on the logger, got %q", logs.String())
+	}
+	if !strings.Contains(logs.String(), "level=DEBUG") || !strings.Contains(logs.String(), "disk I/O error") {
+		t.Errorf("Expected the detail at debug, got %q", logs.String())
+	}
+	if strings.Contains(out.String(), "error") {
+		t.Errorf("Expected no error on stdout, got %q", out.String())
+	}
+	if strings.Contains(logs.String(), "time=") {
+		t.Errorf("Expected no time in the log, got %q", logs.String())
+	}
+
+	// without -v debug records are dropped
+	logs.Reset()
+	c.log = newLogger(&logs, false)
+	c.createUser()
+	if strings.Contains(logs.String(), "level=DEBUG") || strings.Contains(logs.String(), "disk I/O") {
+		t.Errorf("Expected no debug output, got %q", logs.String())
+	}
+
+	// errors the user can fix are shown as they are
+	c, _ = newTestCli(&failingStore{err: userstore.ErrDuplicateUser}, "alice\nalice@test.com\n", time.Second)
+	logs.Reset()
+	c.log = newLogger(&logs, false)
+	c.createUser()
+	if !strings.Contains(logs.String(), `err="User already exists"`) {
+		t.Errorf("Expected the duplicate message, got %q", logs.String())
+	}
+}
+
+// Verbose flag test
+func TestLoadConfigVerbose(t *testing.T) {
+	cfg, err := loadConfig([]string{"-v", "list"}, fakeEnv(nil))
+	if err != nil {
+		t.Fatalf("loadConfig failed : %v", err)
+	}
+	if !cfg.verbose || len(cfg.args) != 1 {
+		t.Errorf("Expected verbose with the list command, got %+v", cfg)
+	}
+}
+
+// Flag errors go to stderr test
+func TestFlagErrorsOnStderr(t *testing.T) {
+	c, out := newTestCli(newStore(t), "", time.Second)
+	var stderr bytes.Buffer
+	c.errOut = &stderr
+
+	if err := c.runCommand([]string{"list", "-bogus"}); err == nil {
+		t.Fatal("Expected an unknown flag to fail")
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected nothing on stdout, got %q", out.String())
+	}
+	if !strings.Contains(stderr.String(), "flag provided but not defined") {
+		t.Errorf("Expected the flag error on stderr, got %q", stderr.String())
+	}
+}
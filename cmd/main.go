@@ -3,122 +3,66 @@ package main
 import (
 	"bufio"
 	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
-	"strconv"
-	"strings"
+	"os/signal"
 
 	"github.com/dotenv213/umm/internal/userstore"
 )
 
-func readLine(scanner *bufio.Scanner, prompt string) string {
-	fmt.Print(prompt)
-	scanner.Scan()
-	return strings.TrimSpace(scanner.Text())
-}
-
 func main() {
-	store, err := userstore.NewDb("users.db")
+	cfg, err := loadConfig(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	// normal output goes to stdout, errors and diagnostics to stderr
+	logger := newLogger(os.Stderr, cfg.verbose)
+	// the store logs its warnings through the default logger
+	slog.SetDefault(logger)
 	if err != nil {
-		log.Fatal(err)
+		logger.Error("invalid configuration", "err", err)
+		os.Exit(1)
 	}
-	defer store.Close()
-
-	scanner := bufio.NewScanner(os.Stdin)
-	ctx := context.Background()
-
-	for {
-		fmt.Println("\n--- User Management System ---")
-		fmt.Println("1. Create User")
-		fmt.Println("2. List All Users")
-		fmt.Println("3. Update User")
-		fmt.Println("4. Delete User")
-		fmt.Println("5. Exit")
-		fmt.Println("Select an option: ")
-
-		scanner.Scan()
-		choice := scanner.Text()
-		switch choice {
-		case "1":
-			uname := readLine(scanner, "Enter Username: ")
-			email := readLine(scanner, "Enter Email: ")
-			if uname == "" || email == "" {
-				fmt.Println("username and email are required")
-				continue
-			}
-			u := &userstore.User{Username: uname, Email: email}
-			if err := store.Create(ctx, u); err != nil {
-				fmt.Printf("Error %v\n", err)
-			} else {
-				fmt.Println("User Created!")
-			}
-		case "2":
-			users, err := store.ListAll(ctx)
-			if err != nil {
-				fmt.Println("failed to list users:", err)
-				continue
-			}
-			fmt.Println("\n  ID  |  Username  |  Email  | Created at  ")
-			for _, u := range users {
-				fmt.Printf("%-3d  |  %-10s  |  %s  |  %v  \n", u.ID, u.Username, u.Email, u.CreatedAt)
-			}
-
-		case "3":
-			idStr := readLine(scanner, "Enter user ID: ")
-			id, err := strconv.ParseInt(idStr, 10, 64)
-			if err != nil {
-				fmt.Println("invalid id")
-				continue
-			}
 
-			u, err := store.GetById(ctx, id)
-			if err != nil {
-				fmt.Println("User not found")
-				continue
-			}
-			newU := readLine(scanner, fmt.Sprintf("Username [%s]: ", u.Username))
-			if newU != "" {
-				u.Username = newU
-			}
-
-			newE := readLine(scanner, fmt.Sprintf("Email [%s]: ", u.Email))
-			if newE != "" {
-				u.Email = newE
-			}
+	store, err := userstore.NewDb(cfg.dbPath, cfg.storeOptions()...)
+	if err != nil {
+		logger.Error("failed to open database", "path", cfg.dbPath, "err", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+	logger.Debug("database opened", "path", cfg.dbPath, "readonly", cfg.readOnly)
 
-			if err := store.Update(ctx, u); err != nil {
-				fmt.Printf("Update failed: %v\n", err)
-			} else {
-				fmt.Println("Updated successfully!")
-			}
-		case "4":
-			idStr := readLine(scanner, "Enter a user ID to delete: ")
-			id, err := strconv.ParseInt(idStr, 10, 64)
-			if err != nil {
-				fmt.Println("Invalid ID format")
-				continue
-			}
+	// Ctrl-C cancels the running operation and the menu
+	// so the store is closed cleanly instead of killed mid transaction
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
-			u, err := store.GetById(ctx, id)
-			if err != nil {
-				fmt.Println("User not found")
-				continue
-			}
+	c := &cli{
+		store:   store,
+		scanner: bufio.NewScanner(os.Stdin),
+		out:     os.Stdout,
+		timeout: cfg.timeout,
+		ctx:     ctx,
+		log:     logger,
+		errOut:  os.Stderr,
+	}
 
-			confirm := readLine(scanner, "Are you sure you want to delete? (y/n): ")
-			if confirm != "y" {
-				continue
-			} else {
-				if err := store.Delete(ctx, u.ID); err != nil {
-					fmt.Println("Delete failed")
-				} else {
-					fmt.Println("User deleted successfuly")
-				}
-			}
-		case "5":
-			fmt.Println("Exiting program...")
-			return
+	// umm export / umm import run once instead of the menu
+	if len(cfg.args) > 0 {
+		err := c.runCommand(cfg.args)
+		store.Close()
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
 		}
+		return
+	}
+
+	c.run(ctx)
+	if ctx.Err() != nil {
+		fmt.Fprintln(c.out, "\nInterrupted, closing the database...")
 	}
 }
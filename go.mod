@@ -2,4 +2,7 @@ module github.com/dotenv213/umm
 
 go 1.25.5
 
-require github.com/mattn/go-sqlite3 v1.14.33
+require (
+	github.com/mattn/go-sqlite3 v1.14.33
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,44 @@
+package userstore
+
+import (
+	"context"
+	"errors"
+)
+
+// EnsureAdmin returns the first admin of s and creates one with username
+// and email when there is none, so it is safe to call on every startup
+// a concurrent startup that creates the same admin first wins
+// and its user is returned here
+func EnsureAdmin(ctx context.Context, s Store, username, email string) (*User, error) {
+	admin, err := firstAdmin(ctx, s)
+	if err != nil || admin != nil {
+		return admin, err
+	}
+
+	user := &User{Username: username, Email: email, Role: RoleAdmin}
+	err = s.Create(ctx, user)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, ErrDuplicateUser) {
+		return nil, err
+	}
+	// the duplicate may be a regular user, then there is still no admin
+	admin, ferr := firstAdmin(ctx, s)
+	if ferr != nil || admin == nil {
+		return nil, err
+	}
+	return admin, nil
+}
+
+// firstAdmin returns the admin with the lowest id, nil if there is none
+func firstAdmin(ctx context.Context, s Store) (*User, error) {
+	admins, err := s.Query(ctx, UserFilter{Role: RoleAdmin, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(admins) == 0 {
+		return nil, nil
+	}
+	return &admins[0], nil
+}
@@ -0,0 +1,46 @@
+package userstore
+
+import (
+	"context"
+	"testing"
+)
+
+// Ensure admin test
+func TestEnsureAdmin(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	_ = store.Create(ctx, &User{Username: "regular", Email: "regular@test.com"})
+
+	admin, err := EnsureAdmin(ctx, store, "admin", "admin@test.com")
+	if err != nil {
+		t.Fatalf("EnsureAdmin failed : %v", err)
+	}
+	if admin.ID == 0 || admin.Role != RoleAdmin || admin.Username != "admin" {
+		t.Fatalf("Expected a new admin, got %+v", admin)
+	}
+
+	// a second startup finds the admin instead of creating another
+	again, err := EnsureAdmin(ctx, store, "other", "other@test.com")
+	if err != nil {
+		t.Fatalf("Second EnsureAdmin failed : %v", err)
+	}
+	if again.ID != admin.ID {
+		t.Errorf("Expected admin %d, got %d", admin.ID, again.ID)
+	}
+	if n, _ := store.Count(ctx); n != 2 {
+		t.Errorf("Expected 2 users, got %d", n)
+	}
+}
+
+// Ensure admin with a taken username test
+func TestEnsureAdminTaken(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	_ = store.Create(ctx, &User{Username: "admin", Email: "someone@test.com"})
+
+	if _, err := EnsureAdmin(ctx, store, "admin", "admin@test.com"); err != ErrDuplicateUser {
+		t.Errorf("Expected duplicate user, got %v", err)
+	}
+}
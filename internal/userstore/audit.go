@@ -0,0 +1,102 @@
+package userstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// audit actions recorded in audit_log
+const (
+	AuditActionCreate     = "create"
+	AuditActionUpdate     = "update"
+	AuditActionDelete     = "delete"
+	AuditActionSoftDelete = "soft_delete"
+)
+
+// actorKey is the context key of the acting user id
+type actorKey struct{}
+
+// ContextWithActor returns a context that records actorID
+// as the user performing the changes made with it
+// audit entries written without an actor have a NULL actor_id (the system)
+func ContextWithActor(ctx context.Context, actorID int64) context.Context {
+	return context.WithValue(ctx, actorKey{}, actorID)
+}
+
+// actorFrom returns the actor set by ContextWithActor or nil
+func actorFrom(ctx context.Context) *int64 {
+	if id, ok := ctx.Value(actorKey{}).(int64); ok {
+		return &id
+	}
+	return nil
+}
+
+// writeAudit stores a snapshot of the user row inside the caller's transaction
+// so the audit entry is committed or rolled back together with the change
+// the actor is taken from ctx
+func (s *sqlStore) writeAudit(ctx context.Context, tx *sql.Tx, action string, user *User) error {
+	// an encrypted email stays encrypted in the snapshot
+	snap := *user
+	var err error
+	if snap.Email, err = s.storedEmail(user.Email); err != nil {
+		return err
+	}
+	snapshot, err := json.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit snapshot : %w", err)
+	}
+	query := `INSERT INTO audit_log (action, user_id, snapshot, actor_id, tenant_id) VALUES (?, ?, ?, ?, ?)`
+	if _, err := tx.ExecContext(ctx, query, action, user.ID, string(snapshot), actorFrom(ctx), s.cfg.tenant); err != nil {
+		return fmt.Errorf("failed to write audit entry : %w", err)
+	}
+	return nil
+}
+
+// readUserTx loads a user row through the transaction
+// used to take snapshots of the stored state
+func (s *sqlStore) readUserTx(ctx context.Context, tx *sql.Tx, id int64) (*User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = ? AND tenant_id = ?`
+	u, err := s.scan(tx.QueryRowContext(ctx, query, id, s.cfg.tenant))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to read user : %w", err)
+	}
+	return &u, nil
+}
+
+// ListAudit returns the audit trail of a user of the store's tenant, oldest first
+// the trail of a deleted user is still returned
+func (s *sqlStore) ListAudit(ctx context.Context, userID int64) ([]AuditEntry, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	query := `SELECT id, action, user_id, snapshot, created_at, actor_id FROM audit_log WHERE user_id = ? AND tenant_id = ? ORDER BY id`
+	rows, err := s.conn().QueryContext(ctx, query, userID, s.cfg.tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries : %w", err)
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		var snapshot string
+		var actor sql.NullInt64
+		if err := rows.Scan(&e.ID, &e.Action, &e.UserID, &snapshot, &e.CreatedAt, &actor); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry : %w", err)
+		}
+		e.Snapshot = json.RawMessage(snapshot)
+		if actor.Valid {
+			e.ActorID = &actor.Int64
+		}
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration : %w", err)
+	}
+	return entries, nil
+}
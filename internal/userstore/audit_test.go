@@ -0,0 +1,144 @@
+package userstore
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+// Audit entry on update test
+func TestAuditOnUpdate(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	u := &User{Username: "a", Email: "a@test.com"}
+	_ = store.Create(ctx, u)
+	u.Email = "new@test.com"
+	if err := store.Update(ctx, u); err != nil {
+		t.Fatalf("Update failed : %v", err)
+	}
+
+	entries, err := store.ListAudit(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("ListAudit failed : %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 audit entries, got %d", len(entries))
+	}
+
+	e := entries[1]
+	if e.Action != AuditActionUpdate || e.UserID != u.ID {
+		t.Fatalf("Unexpected audit entry %s for user %d", e.Action, e.UserID)
+	}
+	var snap User
+	if err := json.Unmarshal(e.Snapshot, &snap); err != nil {
+		t.Fatalf("Bad snapshot : %v", err)
+	}
+	if snap.Email != "new@test.com" {
+		t.Errorf("Expected snapshot email new@test.com, got %s", snap.Email)
+	}
+}
+
+// Unchanged update test
+func TestUpdateUnchanged(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	u := &User{Username: "same", Email: "same@test.com"}
+	_ = store.Create(ctx, u)
+	if err := store.Update(ctx, &User{ID: u.ID, Username: "same", Email: "same@test.com"}); err != nil {
+		t.Fatalf("Update failed : %v", err)
+	}
+
+	entries, _ := store.ListAudit(ctx, u.ID)
+	if len(entries) != 1 {
+		t.Errorf("Expected only the create audit entry, got %d", len(entries))
+	}
+	got, _ := store.GetById(ctx, u.ID)
+	if got.UpdatedAt != nil {
+		t.Errorf("Expected updated_at unchanged, got %v", got.UpdatedAt)
+	}
+}
+
+// Audit entries survive delete test
+func TestAuditOnDelete(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	u := &User{Username: "d", Email: "d@test.com"}
+	_ = store.Create(ctx, u)
+	_ = store.Delete(ctx, u.ID)
+
+	entries, _ := store.ListAudit(ctx, u.ID)
+	if len(entries) != 2 || entries[1].Action != AuditActionDelete {
+		t.Fatalf("Expected create and delete entries, got %v", entries)
+	}
+}
+
+// Failed change leaves no audit entry test
+func TestAuditNotWrittenOnFailure(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	u := &User{Username: "x", Email: "x@test.com"}
+	_ = store.Create(ctx, u)
+	_ = store.Create(ctx, &User{Username: "x", Email: "x@test.com"})
+
+	entries, _ := store.ListAudit(ctx, u.ID)
+	if len(entries) != 1 {
+		t.Errorf("Expected 1 audit entry, got %d", len(entries))
+	}
+}
+
+// Audit records the actor from the context test
+func TestAuditActor(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	admin := &User{Username: "admin", Email: "admin@test.com"}
+	_ = store.Create(ctx, admin)
+	u := &User{Username: "u", Email: "u@test.com"}
+	_ = store.Create(ctx, u)
+
+	u.Email = "changed@test.com"
+	if err := store.Update(ContextWithActor(ctx, admin.ID), u); err != nil {
+		t.Fatalf("Update failed : %v", err)
+	}
+
+	entries, _ := store.ListAudit(ctx, u.ID)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 audit entries, got %d", len(entries))
+	}
+	if entries[0].ActorID != nil {
+		t.Errorf("Expected no actor on create, got %d", *entries[0].ActorID)
+	}
+	if entries[1].ActorID == nil || *entries[1].ActorID != admin.ID {
+		t.Errorf("Expected actor %d on update, got %v", admin.ID, entries[1].ActorID)
+	}
+}
+
+// Audit trail stays in its tenant test
+func TestAuditTenant(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "audit_tenants.db")
+	one := storeAt(t, path, WithTenant(1))
+	two := storeAt(t, path, WithTenant(2))
+
+	u := &User{Username: "private", Email: "private@test.com"}
+	_ = one.Create(ctx, u)
+	u.Username = "renamed"
+	_ = one.Update(ctx, u)
+
+	if entries, err := two.ListAudit(ctx, u.ID); err != nil || len(entries) != 0 {
+		t.Errorf("Expected no audit entries from another tenant, got %v, %v", entries, err)
+	}
+	// the trail of a deleted user stays scoped too
+	_ = one.Delete(ctx, u.ID)
+	if entries, _ := two.ListAudit(ctx, u.ID); len(entries) != 0 {
+		t.Errorf("Expected no audit entries of a deleted user from another tenant, got %v", entries)
+	}
+	if entries, _ := one.ListAudit(ctx, u.ID); len(entries) != 3 {
+		t.Errorf("Expected 3 entries in the own tenant, got %v", entries)
+	}
+}
@@ -0,0 +1,131 @@
+package userstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Backup writes a consistent copy of the database to destPath
+// VACUUM INTO reads a snapshot so writers are not blocked
+func (s *sqlStore) Backup(ctx context.Context, destPath string) error {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	if _, err := os.Stat(destPath); err == nil {
+		if !s.cfg.overwrite {
+			return ErrFileExists
+		}
+		// VACUUM INTO refuses to write over a non-empty file
+		if err := os.Remove(destPath); err != nil {
+			return fmt.Errorf("failed to remove old backup : %w", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to check backup path : %w", err)
+	}
+
+	if _, err := s.db.ExecContext(ctx, `VACUUM INTO ?`, destPath); err != nil {
+		return fmt.Errorf("failed to backup database : %w", err)
+	}
+	return nil
+}
+
+// CloneTo backs the database up to path and opens the copy as a new store
+// with the same options, writes to one never show up in the other
+// except WithReadReplica, the clone reads from its own file
+// path must not exist unless the store was opened WithOverwrite
+func (s *sqlStore) CloneTo(ctx context.Context, path string) (Store, error) {
+	if err := s.Backup(ctx, path); err != nil {
+		return nil, err
+	}
+	cfg := s.cfg
+	return NewDb(path, func(c *config) {
+		*c = cfg
+		c.path = path
+		// the replica belongs to the source, the clone reads its own file
+		c.replica = nil
+	})
+}
+
+// RestoreFrom copies the backup at srcPath to destPath and opens it as a store
+// the backup is checked with PRAGMA integrity_check first, so a corrupt file
+// never replaces the destination
+func RestoreFrom(srcPath, destPath string, opts ...Option) (Store, error) {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if _, err := os.Stat(destPath); err == nil {
+		if !cfg.overwrite {
+			return nil, ErrFileExists
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to check restore path : %w", err)
+	}
+
+	if err := checkIntegrity(srcPath); err != nil {
+		return nil, err
+	}
+
+	if err := copyFile(srcPath, destPath); err != nil {
+		return nil, err
+	}
+	// stale WAL files of the old database would be replayed on the restored one
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(destPath + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to remove %s file : %w", suffix, err)
+		}
+	}
+
+	return NewDb(destPath, opts...)
+}
+
+// checkIntegrity opens path read-only and verifies it
+func checkIntegrity(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("failed to open backup : %w", err)
+	}
+	db, err := sql.Open("sqlite3", "file:"+uriPathEscaper.Replace(path)+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("failed to open backup : %w", err)
+	}
+	defer db.Close()
+
+	return verifyDB(context.Background(), db)
+}
+
+// copyFile copies through a temp file and renames it into place
+// so destPath is never left half written
+func copyFile(srcPath, destPath string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return fmt.Errorf("failed to open backup : %w", err)
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(destPath), filepath.Base(destPath)+".restore-*")
+	if err != nil {
+		return fmt.Errorf("failed to create restore file : %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to copy backup : %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync restore file : %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close restore file : %w", err)
+	}
+	if err := os.Rename(tmp.Name(), destPath); err != nil {
+		return fmt.Errorf("failed to move restore file : %w", err)
+	}
+	return nil
+}
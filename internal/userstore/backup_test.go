@@ -0,0 +1,169 @@
+package userstore
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Backup and reopen test
+func TestBackup(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	_ = store.Create(ctx, &User{Username: "b1", Email: "b1@test.com"})
+	_ = store.Create(ctx, &User{Username: "b2", Email: "b2@test.com"})
+
+	dest := filepath.Join(t.TempDir(), "backup.db")
+	if err := store.Backup(ctx, dest); err != nil {
+		t.Fatalf("Backup failed : %v", err)
+	}
+
+	backup, err := NewDb(dest)
+	if err != nil {
+		t.Fatalf("Open backup failed : %v", err)
+	}
+	defer backup.Close()
+
+	users, err := backup.ListAll(ctx)
+	if err != nil {
+		t.Fatalf("List failed : %v", err)
+	}
+	if len(users) != 2 {
+		t.Errorf("Expected 2 users in backup, got %d", len(users))
+	}
+}
+
+// Clone to a new file test
+func TestCloneTo(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+	u := &User{Username: "src", Email: "src@test.com"}
+	_ = store.Create(ctx, u)
+
+	clone, err := store.CloneTo(ctx, filepath.Join(t.TempDir(), "clone.db"))
+	if err != nil {
+		t.Fatalf("CloneTo failed : %v", err)
+	}
+	defer clone.Close()
+
+	_ = clone.Create(ctx, &User{Username: "extra", Email: "extra@test.com"})
+	cloned, _ := clone.GetById(ctx, u.ID)
+	cloned.Username = "changed"
+	if err := clone.Update(ctx, cloned); err != nil {
+		t.Fatalf("Update clone failed : %v", err)
+	}
+
+	if n, _ := store.Count(ctx); n != 1 {
+		t.Errorf("Expected source to keep 1 user, got %d", n)
+	}
+	got, _ := store.GetById(ctx, u.ID)
+	if got.Username != "src" {
+		t.Errorf("Expected source user unchanged, got %q", got.Username)
+	}
+}
+
+// Backup refuses to overwrite test
+func TestBackupExistingFile(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	dest := filepath.Join(t.TempDir(), "backup.db")
+	if err := os.WriteFile(dest, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Backup(ctx, dest); err != ErrFileExists {
+		t.Fatalf("Expected file exists error, got %v", err)
+	}
+}
+
+// Backup with overwrite test
+func TestBackupOverwrite(t *testing.T) {
+	store := storeWithOptions(t, WithOverwrite(true))
+	ctx := context.Background()
+
+	dest := filepath.Join(t.TempDir(), "backup.db")
+	if err := os.WriteFile(dest, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Backup(ctx, dest); err != nil {
+		t.Fatalf("Backup failed : %v", err)
+	}
+}
+
+// Restore from good backup test
+func TestRestoreFrom(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+	_ = store.Create(ctx, &User{Username: "r1", Email: "r1@test.com"})
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "backup.db")
+	if err := store.Backup(ctx, src); err != nil {
+		t.Fatalf("Backup failed : %v", err)
+	}
+
+	restored, err := RestoreFrom(src, filepath.Join(dir, "restored.db"))
+	if err != nil {
+		t.Fatalf("Restore failed : %v", err)
+	}
+	defer restored.Close()
+
+	users, _ := restored.ListAll(ctx)
+	if len(users) != 1 || users[0].Username != "r1" {
+		t.Errorf("Expected restored user r1, got %v", users)
+	}
+}
+
+// Restore checks the file at a path with uri characters test
+func TestRestoreFromEscapedPath(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "broken#1.db")
+	if err := os.WriteFile(src, []byte("this is not a sqlite database, just junk bytes"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := RestoreFrom(src, filepath.Join(dir, "restored.db"))
+	if !errors.Is(err, ErrCorruptDatabase) {
+		t.Fatalf("Expected corrupt database error, got %v", err)
+	}
+	// an unescaped # would open a new file named broken
+	if _, err := os.Stat(filepath.Join(dir, "broken")); !os.IsNotExist(err) {
+		t.Error("Expected no file to be created at the truncated path")
+	}
+}
+
+// Restore from corrupted file test
+func TestRestoreFromCorrupted(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "broken.db")
+	if err := os.WriteFile(src, []byte("this is not a sqlite database, just junk bytes"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(dir, "restored.db")
+
+	_, err := RestoreFrom(src, dest)
+	if !errors.Is(err, ErrCorruptDatabase) {
+		t.Fatalf("Expected corrupt database error, got %v", err)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Error("Expected destination to not be created")
+	}
+}
+
+// Restore refuses to clobber test
+func TestRestoreExistingDestination(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "users.db")
+	if err := os.WriteFile(dest, []byte("keep"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := RestoreFrom(filepath.Join(dir, "backup.db"), dest); err != ErrFileExists {
+		t.Fatalf("Expected file exists error, got %v", err)
+	}
+}
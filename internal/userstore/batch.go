@@ -0,0 +1,102 @@
+package userstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// CreateResult is the outcome of one user in BatchCreatePartial
+type CreateResult struct {
+	// Index is the position of the user in the batch
+	Index int
+	// ID is the new user id, zero when Err is set
+	ID int64
+	// Err is why the user was not created
+	// a validation error, ErrRateLimited, ErrDuplicateUser or ErrCreatorNotFound
+	Err error
+}
+
+// BatchCreatePartial creates as many of users as it can in one transaction
+// every user gets its own savepoint so a bad row is rolled back alone
+// and reported in its CreateResult instead of failing the batch
+// err is only set when the batch as a whole failed and nothing was created
+func (s *sqlStore) BatchCreatePartial(ctx context.Context, users []*User) (results []CreateResult, err error) {
+	if s.cfg.readOnly {
+		return nil, ErrReadOnly
+	}
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+
+	// rate limit slots of the created users, given back if the batch fails
+	var reserved []func()
+	releaseAll := func() {
+		for _, release := range reserved {
+			release()
+		}
+		reserved = nil
+	}
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		// a busy retry starts the batch over
+		releaseAll()
+		results = make([]CreateResult, len(users))
+		for i, u := range users {
+			results[i] = CreateResult{Index: i}
+			if err := s.validate(u); err != nil {
+				results[i].Err = err
+				continue
+			}
+			release, err := s.reserveCreate(u)
+			if err != nil {
+				results[i].Err = err
+				continue
+			}
+			rowErr, err := s.insertSavepoint(ctx, tx, u)
+			if err != nil {
+				release()
+				return err
+			}
+			if rowErr != nil {
+				release()
+				results[i].Err = rowErr
+				continue
+			}
+			reserved = append(reserved, release)
+			results[i].ID = u.ID
+		}
+		return nil
+	})
+	if err != nil {
+		releaseAll()
+		return nil, err
+	}
+
+	for i, r := range results {
+		if r.Err == nil {
+			s.created(ctx, users[i])
+		}
+	}
+	return results, nil
+}
+
+// insertSavepoint inserts user inside a savepoint of tx
+// rowErr is a failure of this user only, err means the transaction is broken
+func (s *sqlStore) insertSavepoint(ctx context.Context, tx *sql.Tx, user *User) (rowErr, err error) {
+	if _, err := tx.ExecContext(ctx, `SAVEPOINT batch_row`); err != nil {
+		return nil, fmt.Errorf("failed to create savepoint : %w", err)
+	}
+	if err := s.insertUser(ctx, tx, user); err != nil {
+		if !errors.Is(err, ErrDuplicateUser) && !errors.Is(err, ErrCreatorNotFound) {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, `ROLLBACK TO batch_row`); err != nil {
+			return nil, fmt.Errorf("failed to roll back savepoint : %w", err)
+		}
+		rowErr = err
+	}
+	if _, err := tx.ExecContext(ctx, `RELEASE batch_row`); err != nil {
+		return nil, fmt.Errorf("failed to release savepoint : %w", err)
+	}
+	return rowErr, nil
+}
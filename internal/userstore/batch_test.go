@@ -0,0 +1,44 @@
+package userstore
+
+import (
+	"context"
+	"testing"
+)
+
+// Batch create with partial success test
+func TestBatchCreatePartial(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+	_ = store.Create(ctx, &User{Username: "taken", Email: "taken@test.com"})
+
+	users := []*User{
+		{Username: "b1", Email: "b1@test.com"},
+		{Username: "taken", Email: "other@test.com"},
+		{Username: "b2", Email: "b2@test.com", Role: "boss"},
+		{Username: "b3", Email: "b3@test.com"},
+	}
+	results, err := store.BatchCreatePartial(ctx, users)
+	if err != nil {
+		t.Fatalf("BatchCreatePartial failed : %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("Expected 4 results, got %d", len(results))
+	}
+
+	if results[0].Err != nil || results[0].ID == 0 {
+		t.Errorf("Expected first user created, got %+v", results[0])
+	}
+	if results[1].Err != ErrDuplicateUser || results[1].ID != 0 {
+		t.Errorf("Expected duplicate for second user, got %+v", results[1])
+	}
+	if results[2].Err != ErrInvalidRole {
+		t.Errorf("Expected invalid role for third user, got %+v", results[2])
+	}
+	if results[3].Err != nil || results[3].Index != 3 || results[3].ID != users[3].ID {
+		t.Errorf("Expected fourth user created, got %+v", results[3])
+	}
+
+	if n, _ := store.Count(ctx); n != 3 {
+		t.Errorf("Expected 3 users, got %d", n)
+	}
+}
@@ -0,0 +1,224 @@
+package userstore
+
+import (
+	"container/list"
+	"context"
+	"io"
+	"maps"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CachingStore wraps a Store and keeps GetById results in an LRU cache
+// writes through it invalidate what they change, so it is only
+// coherent while every write to the data goes through the same CachingStore
+// writes that touch more users than they name, like Delete detaching
+// the users it created, clear the whole cache
+// BeginStore and WithTx need the wrapped store, not the CachingStore
+type CachingStore struct {
+	Store
+	size int
+	ttl  time.Duration
+	// now is replaced in tests
+	now func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[int64]*list.Element
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+type cacheEntry struct {
+	user    User
+	expires time.Time
+}
+
+// NewCachingStore caches up to size users of store for ttl each
+// the least recently used user is evicted once size is reached
+// a ttl of zero or less keeps users until they are evicted or invalidated
+func NewCachingStore(store Store, size int, ttl time.Duration) *CachingStore {
+	return &CachingStore{
+		Store:   store,
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		order:   list.New(),
+		entries: make(map[int64]*list.Element),
+	}
+}
+
+// GetById returns the cached user or reads it from the wrapped store
+// errors like ErrUserNotFound are not cached
+func (c *CachingStore) GetById(ctx context.Context, id int64) (*User, error) {
+	if u, ok := c.get(id); ok {
+		c.hits.Add(1)
+		return &u, nil
+	}
+	c.misses.Add(1)
+	u, err := c.Store.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	c.put(*u)
+	return u, nil
+}
+
+// CacheStats returns how many GetById calls were served from the cache
+// and how many went to the wrapped store, expired entries count as misses
+func (c *CachingStore) CacheStats() (hits, misses int64) {
+	return c.hits.Load(), c.misses.Load()
+}
+
+// detached copies the metadata map and display name so callers can not change a cached user
+func detached(u User) User {
+	u.Metadata = maps.Clone(u.Metadata)
+	if u.DisplayName != nil {
+		name := *u.DisplayName
+		u.DisplayName = &name
+	}
+	return u
+}
+
+func (c *CachingStore) get(id int64) (User, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[id]
+	if !ok {
+		return User{}, false
+	}
+	e := el.Value.(*cacheEntry)
+	if c.ttl > 0 && !c.now().Before(e.expires) {
+		c.order.Remove(el)
+		delete(c.entries, id)
+		return User{}, false
+	}
+	c.order.MoveToFront(el)
+	return detached(e.user), true
+}
+
+func (c *CachingStore) put(u User) {
+	if c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := &cacheEntry{user: detached(u), expires: c.now().Add(c.ttl)}
+	if el, ok := c.entries[u.ID]; ok {
+		el.Value = e
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[u.ID] = c.order.PushFront(e)
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).user.ID)
+	}
+}
+
+// invalidate drops the cached users with ids
+// it runs after the write even when it failed, a failed write may still
+// have changed the row before the error
+func (c *CachingStore) invalidate(ids ...int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, id := range ids {
+		if el, ok := c.entries[id]; ok {
+			c.order.Remove(el)
+			delete(c.entries, id)
+		}
+	}
+}
+
+// Purge empties the cache
+func (c *CachingStore) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	clear(c.entries)
+}
+
+func (c *CachingStore) Update(ctx context.Context, user *User) error {
+	defer c.invalidate(user.ID)
+	return c.Store.Update(ctx, user)
+}
+
+func (c *CachingStore) UpdateWithDiff(ctx context.Context, user *User) ([]string, error) {
+	defer c.invalidate(user.ID)
+	return c.Store.UpdateWithDiff(ctx, user)
+}
+
+func (c *CachingStore) SetMetadata(ctx context.Context, id int64, kv map[string]any) error {
+	defer c.invalidate(id)
+	return c.Store.SetMetadata(ctx, id, kv)
+}
+
+func (c *CachingStore) Patch(ctx context.Context, id int64, fields map[string]any) error {
+	defer c.invalidate(id)
+	return c.Store.Patch(ctx, id, fields)
+}
+
+func (c *CachingStore) RenameUser(ctx context.Context, id int64, newUsername string) error {
+	defer c.invalidate(id)
+	return c.Store.RenameUser(ctx, id, newUsername)
+}
+
+func (c *CachingStore) SoftDelete(ctx context.Context, id int64) error {
+	defer c.invalidate(id)
+	return c.Store.SoftDelete(ctx, id)
+}
+
+func (c *CachingStore) UpdateRoleMany(ctx context.Context, ids []int64, role string) (int64, error) {
+	defer c.invalidate(ids...)
+	return c.Store.UpdateRoleMany(ctx, ids, role)
+}
+
+func (c *CachingStore) RecordLogin(ctx context.Context, id int64) error {
+	defer c.invalidate(id)
+	return c.Store.RecordLogin(ctx, id)
+}
+
+func (c *CachingStore) MarkEmailVerified(ctx context.Context, id int64) error {
+	defer c.invalidate(id)
+	return c.Store.MarkEmailVerified(ctx, id)
+}
+
+// Delete clears the cache since the users created by id lose their created_by
+func (c *CachingStore) Delete(ctx context.Context, id int64) error {
+	defer c.Purge()
+	return c.Store.Delete(ctx, id)
+}
+
+func (c *CachingStore) PurgeDeleted(ctx context.Context, olderThan time.Duration) (int64, error) {
+	defer c.Purge()
+	return c.Store.PurgeDeleted(ctx, olderThan)
+}
+
+// ConfirmEmailChange clears the cache since the token does not name the user
+func (c *CachingStore) ConfirmEmailChange(ctx context.Context, token string) error {
+	defer c.Purge()
+	return c.Store.ConfirmEmailChange(ctx, token)
+}
+
+func (c *CachingStore) Truncate(ctx context.Context) error {
+	defer c.Purge()
+	return c.Store.Truncate(ctx)
+}
+
+func (c *CachingStore) Renumber(ctx context.Context) error {
+	defer c.Purge()
+	return c.Store.Renumber(ctx)
+}
+
+func (c *CachingStore) MergeUsers(ctx context.Context, keepID, removeID int64) error {
+	defer c.Purge()
+	return c.Store.MergeUsers(ctx, keepID, removeID)
+}
+
+func (c *CachingStore) LoadJSON(ctx context.Context, r io.Reader) error {
+	defer c.Purge()
+	return c.Store.LoadJSON(ctx, r)
+}
@@ -0,0 +1,102 @@
+package userstore
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+var _ Store = (*CachingStore)(nil)
+
+// countingStore counts the GetById calls that reach the database
+type countingStore struct {
+	Store
+	gets int
+}
+
+func (c *countingStore) GetById(ctx context.Context, id int64) (*User, error) {
+	c.gets++
+	return c.Store.GetById(ctx, id)
+}
+
+// Caching store test
+func TestCachingStore(t *testing.T) {
+	ctx := context.Background()
+	counting := &countingStore{Store: StoreTest(t)}
+	store := NewCachingStore(counting, 2, time.Minute)
+
+	u := &User{Username: "cached", Email: "cached@test.com"}
+	_ = store.Create(ctx, u)
+
+	first, err := store.GetById(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("GetById failed : %v", err)
+	}
+	first.Username = "changed by caller"
+	second, _ := store.GetById(ctx, u.ID)
+	if counting.gets != 1 {
+		t.Errorf("Expected second GetById from the cache, got %d reads", counting.gets)
+	}
+	if second.Username != "cached" {
+		t.Errorf("Expected the cached user unchanged, got %s", second.Username)
+	}
+
+	second.Username = "renamed"
+	if err := store.Update(ctx, second); err != nil {
+		t.Fatalf("Update failed : %v", err)
+	}
+	got, _ := store.GetById(ctx, u.ID)
+	if counting.gets != 2 || got.Username != "renamed" {
+		t.Errorf("Expected Update to invalidate, got %s after %d reads", got.Username, counting.gets)
+	}
+
+	if err := store.Delete(ctx, u.ID); err != nil {
+		t.Fatalf("Delete failed : %v", err)
+	}
+	if _, err := store.GetById(ctx, u.ID); err != ErrUserNotFound {
+		t.Errorf("Expected deleted user not found, got %v", err)
+	}
+}
+
+// Caching store eviction and ttl test
+func TestCachingStoreEviction(t *testing.T) {
+	ctx := context.Background()
+	counting := &countingStore{Store: StoreTest(t)}
+	store := NewCachingStore(counting, 2, time.Minute)
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store.now = clock.Now
+
+	var ids []int64
+	for _, name := range []string{"a", "b", "c"} {
+		u := &User{Username: name, Email: name + "@test.com"}
+		_ = store.Create(ctx, u)
+		ids = append(ids, u.ID)
+		_, _ = store.GetById(ctx, u.ID)
+	}
+	// a was the least recently used and is evicted by c
+	_, _ = store.GetById(ctx, ids[0])
+	if counting.gets != 4 {
+		t.Errorf("Expected a to be read again, got %d reads", counting.gets)
+	}
+
+	clock.now = clock.now.Add(2 * time.Minute)
+	_, _ = store.GetById(ctx, ids[0])
+	if counting.gets != 5 {
+		t.Errorf("Expected an expired entry to be read again, got %d reads", counting.gets)
+	}
+}
+
+// Cache stats test
+func TestCacheStats(t *testing.T) {
+	ctx := context.Background()
+	store := NewCachingStore(StoreTest(t), 10, time.Minute)
+
+	u := &User{Username: "stats", Email: "stats@test.com"}
+	_ = store.Create(ctx, u)
+	_, _ = store.GetById(ctx, u.ID)
+	_, _ = store.GetById(ctx, u.ID)
+
+	if hits, misses := store.CacheStats(); hits != 1 || misses != 1 {
+		t.Errorf("Expected 1 hit and 1 miss, got %d and %d", hits, misses)
+	}
+}
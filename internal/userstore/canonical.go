@@ -0,0 +1,45 @@
+package userstore
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// domains that ignore dots in the local part
+var dotlessDomains = map[string]string{
+	"gmail.com":      "gmail.com",
+	"googlemail.com": "gmail.com",
+}
+
+// canonicalEmail lowercases email, drops a +tag from the local part
+// and for gmail also the dots, so aliases of one mailbox compare equal
+func canonicalEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	i := strings.LastIndex(email, "@")
+	if i < 0 {
+		return email
+	}
+	local, domain := email[:i], email[i+1:]
+	if tag := strings.Index(local, "+"); tag >= 0 {
+		local = local[:tag]
+	}
+	if canon, ok := dotlessDomains[domain]; ok {
+		local = strings.ReplaceAll(local, ".", "")
+		domain = canon
+	}
+	return local + "@" + domain
+}
+
+// canonical is the canonical_email value to write for email
+// NULL while canonicalization is off so nothing collides
+// with email encryption only a hash of the canonical form is stored
+func (s *sqlStore) canonical(email string) sql.NullString {
+	if !s.cfg.canonicalEmail || lowerEmail(email) == "" {
+		return sql.NullString{}
+	}
+	canon := canonicalEmail(email)
+	if s.crypt != nil {
+		canon = s.crypt.hash(canon)
+	}
+	return sql.NullString{String: canon, Valid: true}
+}
@@ -0,0 +1,49 @@
+package userstore
+
+import (
+	"context"
+	"testing"
+)
+
+// Canonical email form test
+func TestCanonicalEmail(t *testing.T) {
+	cases := map[string]string{
+		"a.b+x@gmail.com":      "ab@gmail.com",
+		"A.B@GoogleMail.com":   "ab@gmail.com",
+		"first.last+news@x.io": "first.last@x.io",
+		"plain@test.com":       "plain@test.com",
+	}
+	for in, want := range cases {
+		if got := canonicalEmail(in); got != want {
+			t.Errorf("canonicalEmail(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// Canonicalization on and off test
+func TestEmailCanonicalization(t *testing.T) {
+	ctx := context.Background()
+
+	store := storeWithOptions(t, WithEmailCanonicalization(true))
+	if err := store.Create(ctx, &User{Username: "u1", Email: "a.b+x@gmail.com"}); err != nil {
+		t.Fatalf("Create failed : %v", err)
+	}
+	if err := store.Create(ctx, &User{Username: "u2", Email: "ab@gmail.com"}); err != ErrDuplicateUser {
+		t.Errorf("Expected duplicate user with canonicalization, got %v", err)
+	}
+	u3 := &User{Username: "u3", Email: "other@gmail.com"}
+	_ = store.Create(ctx, u3)
+	if err := store.Patch(ctx, u3.ID, map[string]any{"email": "a.b@gmail.com"}); err != ErrDuplicateUser {
+		t.Errorf("Expected duplicate user on patch, got %v", err)
+	}
+	got, _ := store.GetById(ctx, 1)
+	if got.Email != "a.b+x@gmail.com" {
+		t.Errorf("Expected original email kept, got %q", got.Email)
+	}
+
+	off := StoreTest(t)
+	_ = off.Create(ctx, &User{Username: "u1", Email: "a.b+x@gmail.com"})
+	if err := off.Create(ctx, &User{Username: "u2", Email: "ab@gmail.com"}); err != nil {
+		t.Errorf("Expected no collision without canonicalization, got %v", err)
+	}
+}
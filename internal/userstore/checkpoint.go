@@ -0,0 +1,28 @@
+package userstore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Checkpoint copies the WAL into the database file and truncates the WAL to zero bytes
+// it fails when readers or writers keep the checkpoint from finishing
+func (s *sqlStore) Checkpoint(ctx context.Context) error {
+	if s.cfg.readOnly {
+		return ErrReadOnly
+	}
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	var busy, logFrames, checkpointed int
+	err := s.db.QueryRowContext(ctx, `PRAGMA wal_checkpoint(TRUNCATE)`).Scan(&busy, &logFrames, &checkpointed)
+	if err != nil {
+		return fmt.Errorf("failed to checkpoint : %w", err)
+	}
+	// reported as SQLITE_BUSY so the retry backoff applies to it
+	if busy != 0 {
+		return fmt.Errorf("failed to checkpoint : %w", sqlite3.Error{Code: sqlite3.ErrBusy})
+	}
+	return nil
+}
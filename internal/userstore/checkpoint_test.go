@@ -0,0 +1,199 @@
+package userstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Checkpoint test
+func TestCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.db")
+	store := storeAt(t, path, WithAutoCheckpoint(-1))
+	ctx := context.Background()
+
+	for i := 0; i < 20; i++ {
+		_ = store.Create(ctx, &User{Username: fmt.Sprintf("w%d", i), Email: fmt.Sprintf("w%d@test.com", i)})
+	}
+	if info, err := os.Stat(path + "-wal"); err != nil || info.Size() == 0 {
+		t.Fatalf("Expected writes in the WAL, got %v", err)
+	}
+
+	if err := store.Checkpoint(ctx); err != nil {
+		t.Fatalf("Checkpoint failed : %v", err)
+	}
+	info, err := os.Stat(path + "-wal")
+	if err != nil {
+		t.Fatalf("Stat WAL failed : %v", err)
+	}
+	if info.Size() != 0 {
+		t.Errorf("Expected truncated WAL, got %d bytes", info.Size())
+	}
+	if store.Config().AutoCheckpoint != -1 {
+		t.Errorf("Expected auto checkpoint -1, got %d", store.Config().AutoCheckpoint)
+	}
+}
+
+// Checkpoint on close test
+func TestCloseCheckpoints(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "users.db")
+	store, err := NewDb(path, WithAutoCheckpoint(-1))
+	if err != nil {
+		t.Fatalf("Create DB: %v", err)
+	}
+	ctx := context.Background()
+	for i := 0; i < 20; i++ {
+		_ = store.Create(ctx, &User{Username: fmt.Sprintf("c%d", i), Email: fmt.Sprintf("c%d@test.com", i)})
+	}
+
+	// another open connection keeps sqlite from checkpointing on its own
+	other, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("failed to open : %v", err)
+	}
+	defer other.Close()
+	if err := other.Ping(); err != nil {
+		t.Fatalf("failed to connect : %v", err)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed : %v", err)
+	}
+
+	// a copy of the main file alone has every user
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read database : %v", err)
+	}
+	copyPath := filepath.Join(dir, "copy.db")
+	if err := os.WriteFile(copyPath, data, 0o600); err != nil {
+		t.Fatalf("failed to copy database : %v", err)
+	}
+	reopened := storeAt(t, copyPath)
+	if n, err := reopened.Count(ctx); err != nil || n != 20 {
+		t.Errorf("Expected 20 users without the WAL, got %d, %v", n, err)
+	}
+}
+
+// Journal mode test
+func TestJournalMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.db")
+	store := storeAt(t, path, WithJournalMode("delete"))
+
+	var mode string
+	if err := store.(*sqlStore).db.QueryRow(`PRAGMA journal_mode`).Scan(&mode); err != nil {
+		t.Fatalf("Read journal mode failed : %v", err)
+	}
+	if mode != "delete" {
+		t.Errorf("Expected delete journal mode, got %s", mode)
+	}
+	if store.Config().JournalMode != "DELETE" {
+		t.Errorf("Expected DELETE in config, got %s", store.Config().JournalMode)
+	}
+
+	if _, err := NewDb(filepath.Join(t.TempDir(), "bad.db"), WithJournalMode("fast")); err != ErrInvalidJournalMode {
+		t.Errorf("Expected ErrInvalidJournalMode, got %v", err)
+	}
+}
+
+// Failing optional pragma test
+func TestNonStrictPragmas(t *testing.T) {
+	failForeignKeys := func(c *config) {
+		c.pragmaExec = func(conn *sqlite3.SQLiteConn, query string) error {
+			if strings.HasPrefix(query, "PRAGMA foreign_keys") {
+				return errors.New("foreign keys not supported")
+			}
+			_, err := conn.Exec(query, nil)
+			return err
+		}
+	}
+
+	store := storeWithOptions(t, failForeignKeys)
+	ctx := context.Background()
+	u := &User{Username: "degraded", Email: "degraded@test.com"}
+	if err := store.Create(ctx, u); err != nil {
+		t.Fatalf("Create failed : %v", err)
+	}
+	if _, err := store.GetById(ctx, u.ID); err != nil {
+		t.Fatalf("GetById failed : %v", err)
+	}
+
+	if _, err := NewDb(":memory:", failForeignKeys, WithStrictPragmas(true)); err == nil {
+		t.Error("Expected strict pragmas to fail NewDb")
+	}
+}
+
+// Concurrent reads and writes in WAL mode test
+// run it with -race, it checks busy_timeout and the pool keep every call working
+func TestConcurrentReadWrite(t *testing.T) {
+	store := storeAt(t, filepath.Join(t.TempDir(), "concurrent.db"))
+	ctx := context.Background()
+
+	const writers, readers = 4, 4
+	deadline := time.Now().Add(300 * time.Millisecond)
+	var created, next atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				n := next.Add(1)
+				name := fmt.Sprintf("user%d", n)
+				// every tenth create reuses a name to hit the unique index
+				if n%10 == 0 {
+					name = "taken"
+				}
+				err := store.Create(ctx, &User{Username: name, Email: fmt.Sprintf("user%d@test.com", n)})
+				switch {
+				case err == nil:
+					created.Add(1)
+				case !errors.Is(err, ErrDuplicateUser):
+					t.Errorf("Create failed : %v", err)
+					return
+				}
+			}
+		}()
+	}
+	for r := 0; r < readers; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				users, err := store.ListAll(ctx)
+				if err != nil {
+					t.Errorf("ListAll failed : %v", err)
+					return
+				}
+				if len(users) == 0 {
+					continue
+				}
+				id := users[len(users)-1].ID
+				if _, err := store.GetById(ctx, id); err != nil {
+					t.Errorf("GetById %d failed : %v", id, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	n, err := store.Count(ctx)
+	if err != nil {
+		t.Fatalf("Count failed : %v", err)
+	}
+	if n != created.Load() || n == 0 {
+		t.Errorf("Expected %d users, got %d", created.Load(), n)
+	}
+}
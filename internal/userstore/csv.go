@@ -0,0 +1,226 @@
+package userstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvHeader is the header ExportCSV writes
+// ImportCSV only needs the username and email columns
+var csvHeader = []string{"id", "username", "email", "created_at"}
+
+// ExportCSV writes every user as a csv row and returns how many were written
+func (s *sqlStore) ExportCSV(ctx context.Context, w io.Writer) (int, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return 0, fmt.Errorf("failed to write csv header : %w", err)
+	}
+
+	n := 0
+	err := s.Iterate(ctx, func(u User) error {
+		record := []string{
+			strconv.FormatInt(u.ID, 10),
+			u.Username,
+			u.Email,
+			u.CreatedAt.UTC().Format(time.RFC3339),
+		}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("failed to write csv row : %w", err)
+		}
+		n++
+		return nil
+	})
+	if err != nil {
+		return n, err
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return n, fmt.Errorf("failed to write csv : %w", err)
+	}
+	return n, nil
+}
+
+// ImportCSV creates a user for every csv row in one transaction
+// so a bad row leaves the store untouched
+// with skipDuplicates rows that hit a unique constraint are counted and skipped
+// instead of failing the import
+func (s *sqlStore) ImportCSV(ctx context.Context, r io.Reader, skipDuplicates bool) (imported, skipped int, err error) {
+	if s.cfg.readOnly {
+		return 0, 0, ErrReadOnly
+	}
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	cr := csv.NewReader(r)
+	usernameCol, emailCol, err := csvColumns(cr)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// an io.Reader can not be read twice so a busy import is not retried
+	var created []*User
+	// rate limit slots of the created users, given back if the import fails
+	var reserved []func()
+	err = s.runTx(ctx, func(tx *sql.Tx) error {
+		for {
+			record, err := cr.Read()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read csv row : %w", err)
+			}
+			line, _ := cr.FieldPos(0)
+
+			u := &User{
+				Username: strings.TrimSpace(record[usernameCol]),
+				Email:    strings.TrimSpace(record[emailCol]),
+			}
+			if u.Username == "" || (u.Email == "" && !s.cfg.emailOptional) {
+				return fmt.Errorf("line %d : %w", line, ErrInvalidCSV)
+			}
+			if err := s.validate(u); err != nil {
+				return fmt.Errorf("line %d : %w", line, err)
+			}
+			release, err := s.reserveCreate(u)
+			if err != nil {
+				return fmt.Errorf("line %d : %w", line, err)
+			}
+			if err := s.insertUser(ctx, tx, u); err != nil {
+				release()
+				if skipDuplicates && errors.Is(err, ErrDuplicateUser) {
+					skipped++
+					continue
+				}
+				return fmt.Errorf("line %d : %w", line, err)
+			}
+			reserved = append(reserved, release)
+			imported++
+			created = append(created, u)
+		}
+	})
+	if err != nil {
+		for _, release := range reserved {
+			release()
+		}
+		return 0, 0, err
+	}
+	for _, u := range created {
+		s.created(ctx, u)
+	}
+	return imported, skipped, nil
+}
+
+// csvColumns reads the header and finds the username and email columns
+func csvColumns(cr *csv.Reader) (usernameCol, emailCol int, err error) {
+	header, err := cr.Read()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read csv header : %w", err)
+	}
+	usernameCol, emailCol = -1, -1
+	for i, name := range header {
+		switch strings.TrimSpace(strings.ToLower(name)) {
+		case "username":
+			usernameCol = i
+		case "email":
+			emailCol = i
+		}
+	}
+	if usernameCol < 0 || emailCol < 0 {
+		return 0, 0, ErrInvalidCSV
+	}
+	return usernameCol, emailCol, nil
+}
+
+// ValidateCSV checks every row of an ImportCSV file without touching the database
+// the same way ImportCSV does and reports each problem with its line, rows that repeat a username or email
+// of an earlier row in the file are reported with ErrDuplicateUser
+// users already in the store are not looked up
+// the error is for a file that can not be read at all, like a missing header
+func (s *sqlStore) ValidateCSV(ctx context.Context, r io.Reader) (ValidationReport, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	var report ValidationReport
+	cr := csv.NewReader(r)
+	usernameCol, emailCol, err := csvColumns(cr)
+	if err != nil {
+		return report, err
+	}
+
+	// first line of every username and email seen so far
+	usernames := make(map[string]int)
+	emails := make(map[string]int)
+	for {
+		if report.Rows%ctxCheckRows == 0 && ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+		record, err := cr.Read()
+		if err == io.EOF {
+			return report, nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			report.Rows++
+			report.add(parseErr.StartLine, "", fmt.Errorf("%w : %v", ErrInvalidCSV, parseErr.Err))
+			continue
+		}
+		if err != nil {
+			return report, fmt.Errorf("failed to read csv row : %w", err)
+		}
+		report.Rows++
+		line, _ := cr.FieldPos(0)
+
+		u := &User{
+			Username: strings.TrimSpace(record[usernameCol]),
+			Email:    strings.TrimSpace(record[emailCol]),
+		}
+		if u.Username == "" {
+			report.add(line, "username", fmt.Errorf("username is missing : %w", ErrInvalidCSV))
+		}
+		if u.Email == "" && !s.cfg.emailOptional {
+			report.add(line, "email", fmt.Errorf("email is missing : %w", ErrInvalidCSV))
+		}
+		// a missing email is reported above
+		if err := s.validate(u); err != nil && !errors.Is(err, ErrEmailRequired) {
+			report.add(line, invalidField(u, err), err)
+		}
+
+		if u.Username != "" {
+			if first, ok := usernames[u.Username]; ok {
+				report.add(line, "username", fmt.Errorf("same username as line %d : %w", first, ErrDuplicateUser))
+			} else {
+				usernames[u.Username] = line
+			}
+		}
+		// emails are compared the way the store does
+		key := lowerEmail(u.Email)
+		if s.cfg.canonicalEmail {
+			key = canonicalEmail(u.Email)
+		}
+		if key != "" && !s.cfg.emailNotUnique {
+			if first, ok := emails[key]; ok {
+				report.add(line, "email", fmt.Errorf("same email as line %d : %w", first, ErrDuplicateUser))
+			} else {
+				emails[key] = line
+			}
+		}
+	}
+}
+
+// invalidField names the column a validate error is about
+// a csv row has no role so everything but a bad username is about the email
+func invalidField(u *User, err error) string {
+	if errors.Is(err, ErrInvalidCharacter) && strings.ContainsRune(u.Username, 0) {
+		return "username"
+	}
+	return "email"
+}
@@ -0,0 +1,133 @@
+package userstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Export and import round trip test
+func TestCSVRoundTrip(t *testing.T) {
+	src := StoreTest(t)
+	ctx := context.Background()
+	_ = src.Create(ctx, &User{Username: "c1", Email: "c1@test.com"})
+	_ = src.Create(ctx, &User{Username: "c2", Email: "c2@test.com"})
+
+	var buf bytes.Buffer
+	n, err := src.ExportCSV(ctx, &buf)
+	if err != nil {
+		t.Fatalf("Export failed : %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 exported, got %d", n)
+	}
+
+	dst := StoreTest(t)
+	imported, skipped, err := dst.ImportCSV(ctx, &buf, false)
+	if err != nil {
+		t.Fatalf("Import failed : %v", err)
+	}
+	if imported != 2 || skipped != 0 {
+		t.Errorf("Expected 2 imported 0 skipped, got %d %d", imported, skipped)
+	}
+}
+
+// Import skipping duplicates test
+func TestImportCSVSkipDuplicates(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+	_ = store.Create(ctx, &User{Username: "old", Email: "old@test.com"})
+
+	data := "username,email\nold,old@test.com\nnew,new@test.com\n"
+
+	if _, _, err := store.ImportCSV(ctx, strings.NewReader(data), false); !errors.Is(err, ErrDuplicateUser) {
+		t.Fatalf("Expected duplicate error, got %v", err)
+	}
+	if n, _ := store.Count(ctx); n != 1 {
+		t.Fatalf("Expected failed import to be rolled back, got %d users", n)
+	}
+
+	imported, skipped, err := store.ImportCSV(ctx, strings.NewReader(data), true)
+	if err != nil {
+		t.Fatalf("Import failed : %v", err)
+	}
+	if imported != 1 || skipped != 1 {
+		t.Errorf("Expected 1 imported 1 skipped, got %d %d", imported, skipped)
+	}
+}
+
+// Import without required columns test
+func TestImportCSVBadHeader(t *testing.T) {
+	store := StoreTest(t)
+
+	_, _, err := store.ImportCSV(context.Background(), strings.NewReader("name\nx\n"), false)
+	if err != ErrInvalidCSV {
+		t.Fatalf("Expected invalid csv error, got %v", err)
+	}
+}
+
+// Validate csv before import test
+func TestValidateCSV(t *testing.T) {
+	store := storeWithOptions(t, WithDeniedEmailDomains("spam.com"))
+	ctx := context.Background()
+	// the store already has taken@test.com, validation does not look it up
+	_ = store.Create(ctx, &User{Username: "taken", Email: "taken@test.com"})
+
+	input := strings.Join([]string{
+		"username,email",
+		"good,good@test.com",   // line 2
+		",noname@test.com",     // line 3
+		"nomail,",              // line 4
+		"bad,not-an-email",     // line 5
+		"good,other@test.com",  // line 6
+		"copy,GOOD@test.com",   // line 7
+		"taken,taken@test.com", // line 8
+		"short",                // line 9
+		"spam,spam@spam.com",   // line 10
+		"nul\x00,nul@test.com", // line 11
+	}, "\n") + "\n"
+
+	report, err := store.ValidateCSV(ctx, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ValidateCSV failed : %v", err)
+	}
+	want := []struct {
+		line  int
+		field string
+		err   error
+	}{
+		{3, "username", ErrInvalidCSV},
+		{4, "email", ErrInvalidCSV},
+		{5, "email", ErrInvalidEmail},
+		{6, "username", ErrDuplicateUser},
+		{7, "email", ErrDuplicateUser},
+		{9, "", ErrInvalidCSV},
+		{10, "email", ErrEmailDomainNotAllowed},
+		{11, "username", ErrInvalidCharacter},
+	}
+	if report.Rows != 10 || len(report.Problems) != len(want) {
+		t.Fatalf("Expected 10 rows and %d problems, got %d and %+v", len(want), report.Rows, report.Problems)
+	}
+	for i, w := range want {
+		p := report.Problems[i]
+		if p.Line != w.line || p.Field != w.field || !errors.Is(p.Err, w.err) || p.Message == "" {
+			t.Errorf("Expected line %d %q %v, got %+v", w.line, w.field, w.err, p)
+		}
+	}
+	if report.Valid() {
+		t.Error("Expected the report to be invalid")
+	}
+	if n, _ := store.Count(ctx); n != 1 {
+		t.Errorf("Expected validation to leave the store alone, got %d users", n)
+	}
+
+	report, err = store.ValidateCSV(ctx, strings.NewReader("username,email\na,a@test.com\n"))
+	if err != nil || !report.Valid() || report.Rows != 1 {
+		t.Errorf("Expected a valid report, got %+v, %v", report, err)
+	}
+	if _, err := store.ValidateCSV(ctx, strings.NewReader("name,mail\n")); !errors.Is(err, ErrInvalidCSV) {
+		t.Errorf("Expected ErrInvalidCSV for a bad header, got %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package userstore
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// uriPathEscaper keeps characters with a meaning in a file uri inside the path
+var uriPathEscaper = strings.NewReplacer("%", "%25", "?", "%3f", "#", "%23")
+
+// buildDSN is the connection string for path
+// a plain path unless read-only mode or dsn params need the file uri form
+func buildDSN(path string, cfg config) (string, error) {
+	if !cfg.readOnly && len(cfg.dsnParams) == 0 {
+		return path, nil
+	}
+	params := url.Values{}
+	for k, v := range cfg.dsnParams {
+		if !validDSNKey(k) || k == "mode" {
+			return "", fmt.Errorf("%q : %w", k, ErrInvalidDSNParam)
+		}
+		params.Set(k, v)
+	}
+	if cfg.readOnly {
+		// mode=ro makes sqlite itself refuse writes
+		params.Set("mode", "ro")
+	}
+	return "file:" + uriPathEscaper.Replace(path) + "?" + params.Encode(), nil
+}
+
+func validDSNKey(k string) bool {
+	if k == "" {
+		return false
+	}
+	for _, r := range k {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,37 @@
+package userstore
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Dsn params test
+func TestDSNParams(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "odd?name#.db")
+	store := storeAt(t, path, WithDSNParams(map[string]string{"cache": "shared"}))
+	ctx := context.Background()
+
+	u := &User{Username: "shared", Email: "shared@test.com"}
+	if err := store.Create(ctx, u); err != nil {
+		t.Fatalf("Create failed : %v", err)
+	}
+	if _, err := store.GetById(ctx, u.ID); err != nil {
+		t.Fatalf("GetById failed : %v", err)
+	}
+	// the path is escaped, not cut at ? or #
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected the file at the given path : %v", err)
+	}
+	if store.Config().DSNParams["cache"] != "shared" {
+		t.Errorf("Expected cache param in config, got %v", store.Config().DSNParams)
+	}
+
+	for _, params := range []map[string]string{{"cache&mode": "rw"}, {"mode": "rwc"}} {
+		if _, err := NewDb(path, WithDSNParams(params)); !errors.Is(err, ErrInvalidDSNParam) {
+			t.Errorf("Expected ErrInvalidDSNParam for %v, got %v", params, err)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package userstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DumpJSON writes every user, soft deleted ones included, as one json array
+// rows are streamed so memory stays flat for large tables
+func (s *sqlStore) DumpJSON(ctx context.Context, w io.Writer) error {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	query := `SELECT ` + userColumns + ` FROM users WHERE tenant_id = ? ORDER BY id`
+	rows, err := s.conn().QueryContext(ctx, query, s.cfg.tenant)
+	if err != nil {
+		return fmt.Errorf("failed to list users : %w", err)
+	}
+	defer rows.Close()
+
+	sep := "["
+	for rows.Next() {
+		u, err := s.scan(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan user : %w", err)
+		}
+		b, err := json.Marshal(u)
+		if err != nil {
+			return fmt.Errorf("failed to encode user : %w", err)
+		}
+		if _, err := io.WriteString(w, sep+"\n"+string(b)); err != nil {
+			return fmt.Errorf("failed to write json : %w", err)
+		}
+		sep = ","
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error during rows iteration : %w", err)
+	}
+	if sep == "[" {
+		_, err = io.WriteString(w, "[]\n")
+	} else {
+		_, err = io.WriteString(w, "\n]\n")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write json : %w", err)
+	}
+	return nil
+}
+
+// LoadJSON replaces every user with the ones in a DumpJSON array
+// in one transaction, a bad record leaves the store untouched
+// ids, timestamps and the other stored fields are kept as dumped
+// pending email changes and username history of the replaced users are dropped,
+// the audit log is kept as history and no entries are written for the load
+func (s *sqlStore) LoadJSON(ctx context.Context, r io.Reader) error {
+	if s.cfg.readOnly {
+		return ErrReadOnly
+	}
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	var users []User
+	if err := json.NewDecoder(r).Decode(&users); err != nil {
+		return fmt.Errorf("failed to read json : %w", err)
+	}
+	for i := range users {
+		u := &users[i]
+		if u.Username == "" || (u.Email == "" && !s.cfg.emailOptional) {
+			return fmt.Errorf("record %d : username and email are required : %w", i, ErrInvalidField)
+		}
+		if err := s.validate(u); err != nil {
+			return fmt.Errorf("record %d : %w", i, err)
+		}
+		if u.Role == "" {
+			u.Role = RoleUser
+		}
+	}
+
+	// an io.Reader can not be read twice so a busy load is not retried
+	return s.runTx(ctx, func(tx *sql.Tx) error {
+		// created_by may point at a user later in the array
+		if _, err := tx.ExecContext(ctx, `PRAGMA defer_foreign_keys = ON`); err != nil {
+			return fmt.Errorf("failed to defer foreign keys : %w", err)
+		}
+		queries := []string{
+			`DELETE FROM email_changes WHERE user_id IN (SELECT id FROM users WHERE tenant_id = ?)`,
+			`DELETE FROM username_history WHERE user_id IN (SELECT id FROM users WHERE tenant_id = ?)`,
+			`DELETE FROM users WHERE tenant_id = ?`,
+		}
+		for _, q := range queries {
+			if _, err := tx.ExecContext(ctx, q, s.cfg.tenant); err != nil {
+				return fmt.Errorf("failed to clear users : %w", err)
+			}
+		}
+
+		query := `INSERT INTO users (id, username, email, created_at, last_login_at, created_by, uuid, role,
+		deleted_at, email_verified, updated_at, canonical_email, email_hash, email_lower, metadata, display_name, tenant_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		for i, u := range users {
+			email, err := s.storedEmail(u.Email)
+			if err != nil {
+				return err
+			}
+			metadata, err := encodeMetadata(u.Metadata)
+			if err != nil {
+				return err
+			}
+			var id, uuid any
+			if u.ID != 0 {
+				id = u.ID
+			}
+			if u.UUID != "" {
+				uuid = u.UUID
+			}
+			_, err = tx.ExecContext(ctx, query, id, u.Username, email, utcTime(&u.CreatedAt), utcTime(u.LastLoginAt), u.CreatedBy, uuid, u.Role,
+				utcTime(u.DeletedAt), u.EmailVerified, utcTime(u.UpdatedAt), s.canonical(u.Email), s.emailHash(u.Email), s.emailLower(u.Email), metadata, displayName(u.DisplayName), s.cfg.tenant)
+			if err != nil {
+				if isUniqueViolation(err) || isPrimaryKeyViolation(err) {
+					return fmt.Errorf("record %d : %w", i, ErrDuplicateUser)
+				}
+				return fmt.Errorf("record %d : failed to insert user : %w", i, err)
+			}
+		}
+		return nil
+	})
+}
+
+// ExportUser writes one user, soft deleted or not, as "json" or "yaml"
+// yaml has the same field names and order as json
+// an unknown format fails with ErrUnknownFormat before the user is read
+func (s *sqlStore) ExportUser(ctx context.Context, id int64, format string, w io.Writer) error {
+	if format != "json" && format != "yaml" {
+		return fmt.Errorf("%w : %q", ErrUnknownFormat, format)
+	}
+	u, err := s.GetByIdIncludingDeleted(ctx, id)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(u, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode user : %w", err)
+	}
+	if format == "yaml" {
+		// json is yaml, decoding it into a node keeps the key order
+		var node yaml.Node
+		if err := yaml.Unmarshal(b, &node); err != nil {
+			return fmt.Errorf("failed to encode user : %w", err)
+		}
+		clearStyle(&node)
+		if b, err = yaml.Marshal(&node); err != nil {
+			return fmt.Errorf("failed to encode user : %w", err)
+		}
+	} else {
+		b = append(b, '\n')
+	}
+	if _, err := w.Write(b); err != nil {
+		return fmt.Errorf("failed to write user : %w", err)
+	}
+	return nil
+}
+
+// clearStyle drops the json flow style and quoting so the node is written as block yaml
+// strings that would read back as another type stay quoted
+func clearStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		clearStyle(c)
+	}
+}
+
+// utcTime is t in UTC like the store writes it, NULL for nil or the zero time
+func utcTime(t *time.Time) any {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	return t.UTC()
+}
@@ -0,0 +1,127 @@
+package userstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Dump and load round trip test
+func TestDumpLoadJSON(t *testing.T) {
+	ctx := context.Background()
+	src := StoreTest(t)
+
+	admin := &User{Username: "admin", Email: "admin@test.com", Role: RoleAdmin}
+	_ = src.Create(ctx, admin)
+	child := &User{Username: "child", Email: "child@test.com", CreatedBy: &admin.ID}
+	_ = src.Create(ctx, child)
+	gone := &User{Username: "gone", Email: "gone@test.com"}
+	_ = src.Create(ctx, gone)
+	_ = src.SoftDelete(ctx, gone.ID)
+	_ = src.RecordLogin(ctx, admin.ID)
+	_ = src.MarkEmailVerified(ctx, child.ID)
+	// leave a gap in the ids
+	temp := &User{Username: "temp", Email: "temp@test.com"}
+	_ = src.Create(ctx, temp)
+	_ = src.Delete(ctx, temp.ID)
+	_ = src.Create(ctx, &User{Username: "last", Email: "last@test.com"})
+
+	var dump bytes.Buffer
+	if err := src.DumpJSON(ctx, &dump); err != nil {
+		t.Fatalf("DumpJSON failed : %v", err)
+	}
+
+	dst := StoreTest(t)
+	_ = dst.Create(ctx, &User{Username: "replaced", Email: "replaced@test.com"})
+	if err := dst.LoadJSON(ctx, bytes.NewReader(dump.Bytes())); err != nil {
+		t.Fatalf("LoadJSON failed : %v", err)
+	}
+
+	var again bytes.Buffer
+	if err := dst.DumpJSON(ctx, &again); err != nil {
+		t.Fatalf("DumpJSON failed : %v", err)
+	}
+	if again.String() != dump.String() {
+		t.Errorf("Expected the loaded store to dump the same\nwant %s\ngot  %s", dump.String(), again.String())
+	}
+}
+
+// Load with a bad record test
+func TestLoadJSONAtomic(t *testing.T) {
+	ctx := context.Background()
+	store := StoreTest(t)
+	_ = store.Create(ctx, &User{Username: "kept", Email: "kept@test.com"})
+
+	bad := `[{"id": 5, "username": "ok", "email": "ok@test.com"}, {"id": 6, "username": "", "email": "x@test.com"}]`
+	if err := store.LoadJSON(ctx, strings.NewReader(bad)); err == nil {
+		t.Fatal("Expected a record without username to fail")
+	}
+	dup := `[{"id": 5, "username": "a", "email": "a@test.com"}, {"id": 6, "username": "a", "email": "b@test.com"}]`
+	if err := store.LoadJSON(ctx, strings.NewReader(dup)); err == nil || !strings.Contains(err.Error(), "record 1") {
+		t.Fatalf("Expected a duplicate in record 1, got %v", err)
+	}
+
+	users, _ := store.ListAll(ctx)
+	if len(users) != 1 || users[0].Username != "kept" {
+		t.Errorf("Expected the store untouched, got %v", users)
+	}
+}
+
+// Export one user test
+func TestExportUser(t *testing.T) {
+	ctx := context.Background()
+	store := StoreTest(t)
+	u := &User{Username: "support", Email: "support@test.com", Role: RoleAdmin,
+		Metadata: map[string]any{"plan": "pro", "code": "007", "seats": 3.0}}
+	if err := store.Create(ctx, u); err != nil {
+		t.Fatalf("Create failed : %v", err)
+	}
+	_ = store.MarkEmailVerified(ctx, u.ID)
+	want, _ := store.GetById(ctx, u.ID)
+
+	var buf bytes.Buffer
+	if err := store.ExportUser(ctx, u.ID, "json", &buf); err != nil {
+		t.Fatalf("ExportUser json failed : %v", err)
+	}
+	var fromJSON User
+	if err := json.Unmarshal(buf.Bytes(), &fromJSON); err != nil {
+		t.Fatalf("failed to decode json : %v", err)
+	}
+	if !reflect.DeepEqual(&fromJSON, want) {
+		t.Errorf("Expected %+v from json, got %+v", want, fromJSON)
+	}
+
+	buf.Reset()
+	if err := store.ExportUser(ctx, u.ID, "yaml", &buf); err != nil {
+		t.Fatalf("ExportUser yaml failed : %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "id: ") {
+		t.Errorf("Expected block yaml starting with the id, got %q", buf.String())
+	}
+	// the yaml keys are the json ones, so it decodes back through json
+	var fields map[string]any
+	if err := yaml.Unmarshal(buf.Bytes(), &fields); err != nil {
+		t.Fatalf("failed to decode yaml : %v", err)
+	}
+	b, _ := json.Marshal(fields)
+	var fromYAML User
+	if err := json.Unmarshal(b, &fromYAML); err != nil {
+		t.Fatalf("failed to decode yaml fields : %v", err)
+	}
+	if !reflect.DeepEqual(&fromYAML, want) {
+		t.Errorf("Expected %+v from yaml, got %+v", want, fromYAML)
+	}
+
+	if err := store.ExportUser(ctx, 999, "json", &buf); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+	if err := store.ExportUser(ctx, u.ID, "xml", &buf); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Expected ErrUnknownFormat, got %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package userstore
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// emailChangeTTL is how long a change token stays valid
+const emailChangeTTL = 24 * time.Hour
+
+// checkNewEmail runs the format and domain rules on an email
+// that is about to replace the current one
+func (s *sqlStore) checkNewEmail(current *User, newEmail string) error {
+	if err := validateEmailFormat(newEmail); err != nil {
+		return err
+	}
+	changed := *current
+	changed.Email = newEmail
+	return s.validate(&changed)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RequestEmailChange stores newEmail as pending for the user
+// and returns the token that confirms it
+func (s *sqlStore) RequestEmailChange(ctx context.Context, id int64, newEmail string) (string, error) {
+	if s.cfg.readOnly {
+		return "", ErrReadOnly
+	}
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token : %w", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		current, err := s.readUserTx(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if current.DeletedAt != nil {
+			return ErrUserNotFound
+		}
+		if err := s.checkNewEmail(current, newEmail); err != nil {
+			return err
+		}
+		// early feedback, uniqueness is checked again on confirm
+		if !s.cfg.emailNotUnique {
+			var taken bool
+			column, value := s.emailLookup(newEmail)
+			query := `SELECT EXISTS(SELECT 1 FROM users WHERE ` + column + ` = ? AND tenant_id = ?)`
+			if err := tx.QueryRowContext(ctx, query, value, s.cfg.tenant).Scan(&taken); err != nil {
+				return fmt.Errorf("failed to check user : %w", err)
+			}
+			if taken {
+				return ErrDuplicateUser
+			}
+		}
+
+		stored, err := s.storedEmail(newEmail)
+		if err != nil {
+			return err
+		}
+		query := `INSERT INTO email_changes (token_hash, user_id, new_email, expires_at) VALUES (?, ?, ?, ?)`
+		if _, err := tx.ExecContext(ctx, query, hashToken(token), id, stored, s.now().Add(emailChangeTTL)); err != nil {
+			return fmt.Errorf("failed to store email change : %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ConfirmEmailChange applies the pending email of a valid token
+// unknown, used and expired tokens return ErrInvalidToken
+func (s *sqlStore) ConfirmEmailChange(ctx context.Context, token string) error {
+	if s.cfg.readOnly {
+		return ErrReadOnly
+	}
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		var userID int64
+		var newEmail string
+		var expiresAt time.Time
+		query := `SELECT user_id, new_email, expires_at FROM email_changes WHERE token_hash = ?`
+		err := tx.QueryRowContext(ctx, query, hashToken(token)).Scan(&userID, &newEmail, &expiresAt)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrInvalidToken
+			}
+			return fmt.Errorf("failed to read email change : %w", err)
+		}
+		if !s.now().Before(expiresAt) {
+			return ErrInvalidToken
+		}
+		if s.crypt != nil {
+			if newEmail, err = s.crypt.decrypt(newEmail); err != nil {
+				return err
+			}
+		}
+
+		current, err := s.readUserTx(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		// the user may have been soft deleted since the request
+		if current.DeletedAt != nil {
+			return ErrUserNotFound
+		}
+		// rules may have changed since the request
+		if err := s.checkNewEmail(current, newEmail); err != nil {
+			return err
+		}
+
+		stored, err := s.storedEmail(newEmail)
+		if err != nil {
+			return err
+		}
+		// the token reached the new address so it counts as verified
+		query = `UPDATE users SET email = ?, canonical_email = ?, email_hash = ?, email_lower = ?, email_verified = 1, updated_at = ? WHERE id = ?`
+		if _, err := tx.ExecContext(ctx, query, stored, s.canonical(newEmail), s.emailHash(newEmail), s.emailLower(newEmail), s.now().UTC(), userID); err != nil {
+			if isUniqueViolation(err) {
+				return ErrDuplicateUser
+			}
+			return fmt.Errorf("failed to update user : %w", err)
+		}
+		// a token is used once
+		if _, err := tx.ExecContext(ctx, `DELETE FROM email_changes WHERE token_hash = ?`, hashToken(token)); err != nil {
+			return fmt.Errorf("failed to remove email change : %w", err)
+		}
+
+		updated, err := s.readUserTx(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		return s.writeAudit(ctx, tx, AuditActionUpdate, updated)
+	})
+}
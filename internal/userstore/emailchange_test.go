@@ -0,0 +1,166 @@
+package userstore
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock tests move by hand
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+// Email change happy path test
+func TestEmailChange(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	u := &User{Username: "e", Email: "old@test.com"}
+	_ = store.Create(ctx, u)
+
+	token, err := store.RequestEmailChange(ctx, u.ID, "new@test.com")
+	if err != nil {
+		t.Fatalf("Request failed : %v", err)
+	}
+
+	// nothing changes until confirmation
+	got, _ := store.GetById(ctx, u.ID)
+	if got.Email != "old@test.com" {
+		t.Fatalf("Expected email unchanged before confirm, got %s", got.Email)
+	}
+
+	if err := store.ConfirmEmailChange(ctx, token); err != nil {
+		t.Fatalf("Confirm failed : %v", err)
+	}
+	got, _ = store.GetById(ctx, u.ID)
+	if got.Email != "new@test.com" {
+		t.Errorf("Expected new@test.com, got %s", got.Email)
+	}
+
+	if err := store.ConfirmEmailChange(ctx, token); err != ErrInvalidToken {
+		t.Errorf("Expected used token to be invalid, got %v", err)
+	}
+}
+
+// Expired token test
+func TestEmailChangeExpired(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	store := storeWithOptions(t, WithClock(clock.Now))
+	ctx := context.Background()
+
+	u := &User{Username: "e", Email: "old@test.com"}
+	_ = store.Create(ctx, u)
+	token, err := store.RequestEmailChange(ctx, u.ID, "new@test.com")
+	if err != nil {
+		t.Fatalf("Request failed : %v", err)
+	}
+
+	clock.now = clock.now.Add(emailChangeTTL + time.Minute)
+	if err := store.ConfirmEmailChange(ctx, token); err != ErrInvalidToken {
+		t.Fatalf("Expected expired token error, got %v", err)
+	}
+}
+
+// Duplicate new email test
+func TestEmailChangeDuplicate(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	u := &User{Username: "e", Email: "old@test.com"}
+	_ = store.Create(ctx, u)
+	token, err := store.RequestEmailChange(ctx, u.ID, "new@test.com")
+	if err != nil {
+		t.Fatalf("Request failed : %v", err)
+	}
+
+	// someone takes the address before the token is confirmed
+	_ = store.Create(ctx, &User{Username: "other", Email: "new@test.com"})
+	if err := store.ConfirmEmailChange(ctx, token); err != ErrDuplicateUser {
+		t.Fatalf("Expected duplicate user error, got %v", err)
+	}
+
+	if _, err := store.RequestEmailChange(ctx, u.ID, "not an email"); err != ErrInvalidEmail {
+		t.Errorf("Expected invalid email error, got %v", err)
+	}
+}
+
+// Email change audit snapshot test
+func TestEmailChangeAudit(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	store := storeWithOptions(t, WithClock(clock.Now))
+	ctx := context.Background()
+
+	u := &User{Username: "e", Email: "old@test.com"}
+	_ = store.Create(ctx, u)
+	token, err := store.RequestEmailChange(ctx, u.ID, "new@test.com")
+	if err != nil {
+		t.Fatalf("Request failed : %v", err)
+	}
+	clock.now = clock.now.Add(time.Hour)
+	if err := store.ConfirmEmailChange(ctx, token); err != nil {
+		t.Fatalf("Confirm failed : %v", err)
+	}
+
+	entries, err := store.ListAudit(ctx, u.ID)
+	if err != nil || len(entries) == 0 {
+		t.Fatalf("ListAudit failed : %v", err)
+	}
+	var snap User
+	if err := json.Unmarshal(entries[len(entries)-1].Snapshot, &snap); err != nil {
+		t.Fatalf("Bad snapshot : %v", err)
+	}
+	if snap.Email != "new@test.com" || !snap.EmailVerified || snap.UpdatedAt == nil || !snap.UpdatedAt.Equal(clock.now) {
+		t.Errorf("Expected the stored row in the snapshot, got %+v", snap)
+	}
+}
+
+// Email change of a soft deleted user test
+func TestEmailChangeSoftDeleted(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	u := &User{Username: "e", Email: "old@test.com"}
+	_ = store.Create(ctx, u)
+	token, err := store.RequestEmailChange(ctx, u.ID, "new@test.com")
+	if err != nil {
+		t.Fatalf("Request failed : %v", err)
+	}
+	if err := store.SoftDelete(ctx, u.ID); err != nil {
+		t.Fatalf("SoftDelete failed : %v", err)
+	}
+
+	if _, err := store.RequestEmailChange(ctx, u.ID, "other@test.com"); err != ErrUserNotFound {
+		t.Errorf("Expected not found for a soft deleted user, got %v", err)
+	}
+	if err := store.ConfirmEmailChange(ctx, token); err != ErrUserNotFound {
+		t.Errorf("Expected confirm to not find a soft deleted user, got %v", err)
+	}
+}
+
+// Email change without unique emails test
+func TestEmailChangeNotUnique(t *testing.T) {
+	store := storeWithOptions(t, WithEmailUnique(false))
+	ctx := context.Background()
+
+	u := &User{Username: "e", Email: "old@test.com"}
+	_ = store.Create(ctx, u)
+	_ = store.Create(ctx, &User{Username: "other", Email: "shared@test.com"})
+
+	token, err := store.RequestEmailChange(ctx, u.ID, "shared@test.com")
+	if err != nil {
+		t.Fatalf("Expected a shared email to be allowed, got %v", err)
+	}
+	if err := store.ConfirmEmailChange(ctx, token); err != nil {
+		t.Fatalf("Confirm failed : %v", err)
+	}
+	got, _ := store.GetById(ctx, u.ID)
+	if got.Email != "shared@test.com" {
+		t.Errorf("Expected shared@test.com, got %s", got.Email)
+	}
+}
@@ -0,0 +1,159 @@
+package userstore
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// emailCipher encrypts emails for WithEmailEncryption
+// and hashes them so lookups and uniqueness work without decrypting
+type emailCipher struct {
+	aead   cipher.AEAD
+	macKey []byte
+}
+
+// newEmailCipher derives separate encryption and hash keys from key
+// key must be 16, 24 or 32 bytes like an AES key
+func newEmailCipher(key []byte) (*emailCipher, error) {
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, ErrInvalidKey
+	}
+	block, err := aes.NewCipher(deriveKey(key, "umm email encryption"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create email cipher : %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create email cipher : %w", err)
+	}
+	return &emailCipher{aead: aead, macKey: deriveKey(key, "umm email hash")}, nil
+}
+
+func deriveKey(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// encrypt returns base64 of a random nonce followed by the sealed email
+func (c *emailCipher) encrypt(email string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce : %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(email), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (c *emailCipher) decrypt(stored string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(stored)
+	if err != nil || len(raw) < c.aead.NonceSize() {
+		return "", fmt.Errorf("failed to decrypt email : %w", ErrCorruptDatabase)
+	}
+	nonce, sealed := raw[:c.aead.NonceSize()], raw[c.aead.NonceSize():]
+	plain, err := c.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt email : %w", err)
+	}
+	return string(plain), nil
+}
+
+// hash is the keyed hash of the trimmed lowercased email
+// so Foo@x.com and foo@x.com collide like one address
+func (c *emailCipher) hash(email string) string {
+	mac := hmac.New(sha256.New, c.macKey)
+	mac.Write([]byte(lowerEmail(email)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// storedEmail is the value written to the email column
+func (s *sqlStore) storedEmail(email string) (string, error) {
+	if s.crypt == nil || email == "" {
+		return email, nil
+	}
+	return s.crypt.encrypt(email)
+}
+
+// emailHash is the value written to email_hash, NULL without encryption or an email
+func (s *sqlStore) emailHash(email string) sql.NullString {
+	if s.crypt == nil || lowerEmail(email) == "" {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: s.crypt.hash(email), Valid: true}
+}
+
+// lowerEmail is the form emails are compared in, trimmed and lowercased
+func lowerEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// emailLower is the value written to email_lower, NULL with encryption
+// where email_hash already ignores case, and for users without an email
+func (s *sqlStore) emailLower(email string) sql.NullString {
+	if s.crypt != nil || lowerEmail(email) == "" {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: lowerEmail(email), Valid: true}
+}
+
+// emailLookup is the column and value that find a user by email
+// both ignore case while the email column keeps it as typed
+func (s *sqlStore) emailLookup(email string) (column string, value string) {
+	if s.crypt == nil {
+		return "email_lower", lowerEmail(email)
+	}
+	return "email_hash", s.crypt.hash(email)
+}
+
+// scan reads a user row and decrypts its email
+// an empty email was stored as is
+func (s *sqlStore) scan(row rowScanner) (User, error) {
+	u, err := scanUser(row)
+	if err != nil || s.crypt == nil || u.Email == "" {
+		return u, err
+	}
+	u.Email, err = s.crypt.decrypt(u.Email)
+	return u, err
+}
+
+// countByDomainDecrypted is CountByDomain for encrypted emails
+// sql can not see the domain so every row is decrypted
+func (s *sqlStore) countByDomainDecrypted(ctx context.Context) (map[string]int64, error) {
+	counts := make(map[string]int64)
+	err := s.Iterate(ctx, func(u User) error {
+		counts[emailDomain(u.Email)]++
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count users by domain : %w", err)
+	}
+	return counts, nil
+}
+
+// GetByEmail returns the user with email, ignoring case
+// the user is returned with the email as it was stored
+func (s *sqlStore) GetByEmail(ctx context.Context, email string) (*User, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	column, value := s.emailLookup(email)
+	query := `SELECT ` + userColumns + ` FROM users WHERE ` + column + ` = ? AND tenant_id = ? AND deleted_at IS NULL`
+	u, err := s.scan(s.conn().QueryRowContext(ctx, query, value, s.cfg.tenant))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user by email : %w", err)
+	}
+	return &u, nil
+}
@@ -0,0 +1,74 @@
+package userstore
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+var testEmailKey = bytes.Repeat([]byte{7}, 32)
+
+// Encrypted email round trip test
+func TestEmailEncryption(t *testing.T) {
+	ctx := context.Background()
+	store := storeWithOptions(t, WithEmailEncryption(testEmailKey))
+
+	u := &User{Username: "crypt", Email: "Crypt@Test.com"}
+	if err := store.Create(ctx, u); err != nil {
+		t.Fatalf("Create failed : %v", err)
+	}
+
+	var raw string
+	if err := store.(*sqlStore).db.QueryRow(`SELECT email FROM users WHERE id = ?`, u.ID).Scan(&raw); err != nil {
+		t.Fatalf("failed to read raw email : %v", err)
+	}
+	if strings.Contains(strings.ToLower(raw), "crypt@test.com") {
+		t.Errorf("Expected encrypted email column, got %q", raw)
+	}
+
+	got, err := store.GetById(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("GetById failed : %v", err)
+	}
+	if got.Email != "Crypt@Test.com" {
+		t.Errorf("Expected decrypted email, got %q", got.Email)
+	}
+
+	byEmail, err := store.GetByEmail(ctx, "crypt@test.com")
+	if err != nil || byEmail.ID != u.ID {
+		t.Errorf("Expected GetByEmail to find user %d, got %v %v", u.ID, byEmail, err)
+	}
+	if ok, _ := store.ExistsByEmail(ctx, "CRYPT@test.com"); !ok {
+		t.Errorf("Expected ExistsByEmail true through the hash")
+	}
+
+	if err := store.Create(ctx, &User{Username: "other", Email: "crypt@test.com"}); err != ErrDuplicateUser {
+		t.Errorf("Expected duplicate user for same email, got %v", err)
+	}
+
+	if err := store.Patch(ctx, u.ID, map[string]any{"email": "new@test.com"}); err != nil {
+		t.Fatalf("Patch failed : %v", err)
+	}
+	if _, err := store.GetByEmail(ctx, "crypt@test.com"); err != ErrUserNotFound {
+		t.Errorf("Expected old email gone after patch, got %v", err)
+	}
+	if got, err := store.GetByEmail(ctx, "new@test.com"); err != nil || got.Email != "new@test.com" {
+		t.Errorf("Expected patched email, got %v %v", got, err)
+	}
+
+	counts, err := store.CountByDomain(ctx)
+	if err != nil {
+		t.Fatalf("CountByDomain failed : %v", err)
+	}
+	if counts["test.com"] != 1 {
+		t.Errorf("Expected 1 user on test.com, got %v", counts)
+	}
+}
+
+// Encryption key length test
+func TestEmailEncryptionInvalidKey(t *testing.T) {
+	if _, err := NewDb(":memory:", WithEmailEncryption([]byte("short"))); err != ErrInvalidKey {
+		t.Errorf("Expected ErrInvalidKey, got %v", err)
+	}
+}
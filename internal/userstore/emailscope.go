@@ -0,0 +1,54 @@
+package userstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// emailIndexes keep emails unique per tenant, see WithEmailUnique
+// users without an email never collide, email_lower, email_hash and
+// canonical_email are NULL for them and the email index leaves empty ones out
+var emailIndexes = []struct {
+	name, columns string
+}{
+	{"idx_users_tenant_email", "(tenant_id, email) WHERE email <> ''"},
+	{"idx_users_tenant_email_lower", "(tenant_id, email_lower)"},
+	{"idx_users_tenant_email_hash", "(tenant_id, email_hash)"},
+	{"idx_users_tenant_canonical_email", "(tenant_id, canonical_email)"},
+}
+
+// applyEmailScope makes the email indexes unique or plain to match WithEmailUnique
+// lookups by email stay indexed either way
+// making them unique again fails with ErrDuplicateUser while duplicates are stored
+func (s *sqlStore) applyEmailScope(ctx context.Context, q querier) error {
+	unique := !s.cfg.emailNotUnique
+	for _, idx := range emailIndexes {
+		var isUnique bool
+		err := q.QueryRowContext(ctx, `SELECT "unique" FROM pragma_index_list('users') WHERE name = ?`, idx.name).Scan(&isUnique)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to read index %s : %w", idx.name, err)
+		}
+		if err == nil && isUnique == unique {
+			continue
+		}
+		kind := "INDEX"
+		if unique {
+			kind = "UNIQUE INDEX"
+		}
+		queries := []string{
+			`DROP INDEX IF EXISTS ` + idx.name,
+			`CREATE ` + kind + ` ` + idx.name + ` ON users ` + idx.columns,
+		}
+		for _, query := range queries {
+			if _, err := q.ExecContext(ctx, query); err != nil {
+				if isUniqueViolation(err) {
+					return fmt.Errorf("failed to make %s unique : %w", idx.name, ErrDuplicateUser)
+				}
+				return fmt.Errorf("failed to change index %s : %w", idx.name, err)
+			}
+		}
+	}
+	return nil
+}
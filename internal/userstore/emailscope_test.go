@@ -0,0 +1,93 @@
+package userstore
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// Email required and unique by default test
+func TestEmailScopeDefault(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	if err := store.Create(ctx, &User{Username: "noemail"}); !errors.Is(err, ErrEmailRequired) {
+		t.Errorf("Expected ErrEmailRequired, got %v", err)
+	}
+	_ = store.Create(ctx, &User{Username: "first", Email: "same@test.com"})
+	if err := store.Create(ctx, &User{Username: "second", Email: "same@test.com"}); !errors.Is(err, ErrDuplicateUser) {
+		t.Errorf("Expected ErrDuplicateUser, got %v", err)
+	}
+}
+
+// Email optional test
+func TestEmailOptional(t *testing.T) {
+	store := storeWithOptions(t, WithEmailRequired(false))
+	ctx := context.Background()
+
+	for _, name := range []string{"svc-a", "svc-b"} {
+		u := &User{Username: name}
+		if err := store.Create(ctx, u); err != nil {
+			t.Fatalf("Create %s failed : %v", name, err)
+		}
+		got, err := store.GetById(ctx, u.ID)
+		if err != nil || got.Email != "" {
+			t.Errorf("Expected %s without an email, got %+v, %v", name, got, err)
+		}
+	}
+	// emails that are set are still unique
+	_ = store.Create(ctx, &User{Username: "first", Email: "same@test.com"})
+	if err := store.Create(ctx, &User{Username: "second", Email: "SAME@test.com"}); !errors.Is(err, ErrDuplicateUser) {
+		t.Errorf("Expected ErrDuplicateUser, got %v", err)
+	}
+
+	// encrypted stores keep the empty email readable
+	crypt := storeWithOptions(t, WithEmailRequired(false), WithEmailEncryption(testEmailKey))
+	u := &User{Username: "svc"}
+	if err := crypt.Create(ctx, u); err != nil {
+		t.Fatalf("Create with encryption failed : %v", err)
+	}
+	if got, err := crypt.GetById(ctx, u.ID); err != nil || got.Email != "" {
+		t.Errorf("Expected an empty email, got %+v, %v", got, err)
+	}
+	_ = crypt.Create(ctx, &User{Username: "svc2"})
+	if n, _ := crypt.Count(ctx); n != 2 {
+		t.Errorf("Expected 2 users, got %d", n)
+	}
+}
+
+// Email not unique test
+func TestEmailNotUnique(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shared.db")
+	store := storeAt(t, path, WithEmailUnique(false), WithEmailRequired(false))
+	ctx := context.Background()
+
+	users := []*User{
+		{Username: "a", Email: "shared@test.com"},
+		{Username: "b", Email: "Shared@test.com"},
+		{Username: "c"},
+		{Username: "d"},
+	}
+	for _, u := range users {
+		if err := store.Create(ctx, u); err != nil {
+			t.Fatalf("Create %s failed : %v", u.Username, err)
+		}
+	}
+	if ok, err := store.IsEmailAvailable(ctx, "shared@test.com"); err != nil || !ok {
+		t.Errorf("Expected a shared email to stay available, got %v, %v", ok, err)
+	}
+	if _, err := store.GetByEmail(ctx, "shared@test.com"); err != nil {
+		t.Errorf("GetByEmail failed : %v", err)
+	}
+	// usernames stay unique
+	if err := store.Create(ctx, &User{Username: "a", Email: "other@test.com"}); !errors.Is(err, ErrDuplicateUser) {
+		t.Errorf("Expected ErrDuplicateUser for the username, got %v", err)
+	}
+	_ = store.Close()
+
+	// the unique indexes can not come back while emails are shared
+	if _, err := NewDb(path); !errors.Is(err, ErrDuplicateUser) {
+		t.Errorf("Expected ErrDuplicateUser when reopening unique, got %v", err)
+	}
+}
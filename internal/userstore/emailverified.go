@@ -0,0 +1,56 @@
+package userstore
+
+import (
+	"context"
+	"fmt"
+)
+
+// MarkEmailVerified records that the user confirmed owning their email
+func (s *sqlStore) MarkEmailVerified(ctx context.Context, id int64) error {
+	if s.cfg.readOnly {
+		return ErrReadOnly
+	}
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	query := `UPDATE users SET email_verified = 1 WHERE id = ? AND tenant_id = ? AND deleted_at IS NULL`
+	result, err := s.conn().ExecContext(ctx, query, id, s.cfg.tenant)
+	if err != nil {
+		return fmt.Errorf("failed to mark email verified : %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// ListUnverified returns the users that have not verified their email ordered by id
+// meant for reminder jobs
+func (s *sqlStore) ListUnverified(ctx context.Context) ([]User, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	query := `SELECT ` + userColumns + ` FROM users WHERE email_verified = 0 AND tenant_id = ? AND deleted_at IS NULL ORDER BY id`
+	rows, err := s.conn().QueryContext(ctx, query, s.cfg.tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unverified users : %w", err)
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		u, err := s.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user : %w", err)
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration : %w", err)
+	}
+	return users, nil
+}
@@ -0,0 +1,45 @@
+package userstore
+
+import (
+	"context"
+	"testing"
+)
+
+// Mark email verified test
+func TestMarkEmailVerified(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+	u := &User{Username: "v", Email: "v@test.com"}
+	_ = store.Create(ctx, u)
+
+	got, _ := store.GetById(ctx, u.ID)
+	if got.EmailVerified {
+		t.Fatal("Expected a new user to be unverified")
+	}
+	if unverified, _ := store.ListUnverified(ctx); len(unverified) != 1 {
+		t.Errorf("Expected 1 unverified user, got %d", len(unverified))
+	}
+
+	if err := store.MarkEmailVerified(ctx, u.ID); err != nil {
+		t.Fatalf("MarkEmailVerified failed : %v", err)
+	}
+	got, _ = store.GetById(ctx, u.ID)
+	if !got.EmailVerified {
+		t.Error("Expected user to be verified")
+	}
+	if unverified, _ := store.ListUnverified(ctx); len(unverified) != 0 {
+		t.Errorf("Expected no unverified users, got %d", len(unverified))
+	}
+
+	// a different email has to be verified again
+	got.Email = "other@test.com"
+	_ = store.Update(ctx, got)
+	got, _ = store.GetById(ctx, u.ID)
+	if got.EmailVerified {
+		t.Error("Expected changing the email to reset verification")
+	}
+
+	if err := store.MarkEmailVerified(ctx, 999); err != ErrUserNotFound {
+		t.Errorf("Expected user not found, got %v", err)
+	}
+}
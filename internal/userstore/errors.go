@@ -1,8 +1,65 @@
 package userstore
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/mattn/go-sqlite3"
+)
 
 var (
 	ErrUserNotFound = errors.New("User not found")
 	ErrDuplicateUser = errors.New("User already exists")
-)
\ No newline at end of file
+	ErrInvalidPagination = errors.New("limit and offset must not be negative")
+	ErrFileExists = errors.New("destination file already exists")
+	ErrCorruptDatabase = errors.New("database integrity check failed")
+	ErrEmailDomainNotAllowed = errors.New("email domain is not allowed")
+	ErrInvalidCSV = errors.New("csv needs username and email values")
+	ErrReadOnly = errors.New("store is read-only")
+	ErrSchemaMissing = errors.New("users table does not exist")
+	ErrInvalidEmail = errors.New("invalid email address")
+	ErrInvalidToken = errors.New("invalid or expired token")
+	ErrCreatorNotFound = errors.New("created_by user does not exist")
+	ErrRateLimited = errors.New("too many accounts created for this email domain")
+	ErrMergeSameUser = errors.New("can not merge a user into itself")
+	ErrFullTextUnavailable = errors.New("full-text search is not available")
+	ErrInvalidRole = errors.New("invalid role")
+	ErrInvalidField = errors.New("unknown field or wrong value type")
+	ErrInvalidKey = errors.New("encryption key must be 16, 24 or 32 bytes")
+	ErrInvalidCharacter = errors.New("username and email must not contain a null byte")
+	ErrLimitTooLarge = errors.New("limit is larger than the max page size")
+	ErrInvalidJournalMode = errors.New("unknown journal mode")
+	ErrInvalidIDOffset = errors.New("id offset must be positive")
+	ErrInvalidMetadata = errors.New("stored metadata is not a json object")
+	ErrInvalidDSNParam = errors.New("invalid dsn parameter")
+	ErrSchemaMismatch = errors.New("users table does not match the expected schema")
+	ErrEmailRequired = errors.New("email is required")
+	ErrUnknownFormat = errors.New("unknown export format")
+)
+
+// isUniqueViolation checks the driver's extended error code
+// instead of the message text, which can change between versions
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
+
+// isPrimaryKeyViolation reports an insert with an id that is taken
+func isPrimaryKeyViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+}
+
+// isForeignKeyViolation reports a reference to a row that does not exist
+func isForeignKeyViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.ExtendedCode == sqlite3.ErrConstraintForeignKey
+}
@@ -0,0 +1,234 @@
+// Package fakestore is an in memory userstore.Store for handler tests
+// it records every call and can be told to fail the next create
+package fakestore
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dotenv213/umm/internal/userstore"
+)
+
+// Call is one recorded method call
+type Call struct {
+	Method string
+	Args   []any
+}
+
+// FakeStore keeps users in a map and implements the common CRUD methods
+// the other methods of userstore.Store return ErrNotImplemented
+type FakeStore struct {
+	mu         sync.Mutex
+	users      map[int64]userstore.User
+	nextID     int64
+	calls      []Call
+	createErrs []error
+	// Now stamps CreatedAt and UpdatedAt, time.Now when nil
+	Now func() time.Time
+}
+
+// a method added to userstore.Store needs a stub here before the build passes
+var _ userstore.Store = (*FakeStore)(nil)
+
+// New returns an empty FakeStore
+func New() *FakeStore {
+	return &FakeStore{users: make(map[int64]userstore.User), nextID: 1}
+}
+
+// FailNextCreate makes the next Create return err without storing anything
+// calling it several times fails that many creates in order
+func (f *FakeStore) FailNextCreate(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.createErrs = append(f.createErrs, err)
+}
+
+// Calls returns a copy of the recorded calls, oldest first
+func (f *FakeStore) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// Reset forgets the recorded calls and pending failures but keeps the users
+func (f *FakeStore) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = nil
+	f.createErrs = nil
+}
+
+// record must be called with mu held
+func (f *FakeStore) record(method string, args ...any) {
+	f.calls = append(f.calls, Call{Method: method, Args: args})
+}
+
+func (f *FakeStore) now() time.Time {
+	if f.Now != nil {
+		return f.Now()
+	}
+	return time.Now()
+}
+
+// taken reports whether another user than id has the username or email
+// emails ignore case like the store does
+func (f *FakeStore) taken(id int64, username, email string) bool {
+	for _, u := range f.users {
+		if u.ID != id && (u.Username == username || strings.EqualFold(u.Email, email)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *FakeStore) Create(ctx context.Context, user *userstore.User) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("Create", user.Username, user.Email)
+	if len(f.createErrs) > 0 {
+		err := f.createErrs[0]
+		f.createErrs = f.createErrs[1:]
+		return err
+	}
+	if f.taken(0, user.Username, user.Email) {
+		return userstore.ErrDuplicateUser
+	}
+	if user.Role == "" {
+		user.Role = userstore.RoleUser
+	}
+	user.ID = f.nextID
+	user.CreatedAt = f.now().UTC()
+	f.nextID++
+	f.users[user.ID] = *user
+	return nil
+}
+
+func (f *FakeStore) GetById(ctx context.Context, id int64) (*userstore.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("GetById", id)
+	u, ok := f.users[id]
+	if !ok {
+		return nil, userstore.ErrUserNotFound
+	}
+	return &u, nil
+}
+
+func (f *FakeStore) GetByEmail(ctx context.Context, email string) (*userstore.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("GetByEmail", email)
+	for _, u := range f.users {
+		if strings.EqualFold(u.Email, email) {
+			return &u, nil
+		}
+	}
+	return nil, userstore.ErrUserNotFound
+}
+
+func (f *FakeStore) ExistsByEmail(ctx context.Context, email string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("ExistsByEmail", email)
+	for _, u := range f.users {
+		if strings.EqualFold(u.Email, email) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *FakeStore) ExistsByUsername(ctx context.Context, username string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("ExistsByUsername", username)
+	for _, u := range f.users {
+		if u.Username == username {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// sorted returns the users ordered by id, mu must be held
+func (f *FakeStore) sorted() []userstore.User {
+	users := make([]userstore.User, 0, len(f.users))
+	for id := int64(1); id < f.nextID; id++ {
+		if u, ok := f.users[id]; ok {
+			users = append(users, u)
+		}
+	}
+	return users
+}
+
+func (f *FakeStore) ListAll(ctx context.Context) ([]userstore.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("ListAll")
+	return f.sorted(), nil
+}
+
+func (f *FakeStore) List(ctx context.Context, limit, offset int) ([]userstore.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("List", limit, offset)
+	if limit < 0 || offset < 0 {
+		return nil, userstore.ErrInvalidPagination
+	}
+	users := f.sorted()
+	if offset > len(users) {
+		offset = len(users)
+	}
+	users = users[offset:]
+	if limit < len(users) {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func (f *FakeStore) Count(ctx context.Context) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("Count")
+	return int64(len(f.users)), nil
+}
+
+func (f *FakeStore) Update(ctx context.Context, user *userstore.User) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("Update", user.ID, user.Username, user.Email)
+	stored, ok := f.users[user.ID]
+	if !ok {
+		return userstore.ErrUserNotFound
+	}
+	if f.taken(user.ID, user.Username, user.Email) {
+		return userstore.ErrDuplicateUser
+	}
+	if !strings.EqualFold(user.Email, stored.Email) {
+		stored.EmailVerified = false
+	}
+	now := f.now().UTC()
+	stored.Username, stored.Email, stored.UpdatedAt = user.Username, user.Email, &now
+	f.users[user.ID] = stored
+	return nil
+}
+
+func (f *FakeStore) Delete(ctx context.Context, id int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("Delete", id)
+	if _, ok := f.users[id]; !ok {
+		return userstore.ErrUserNotFound
+	}
+	delete(f.users, id)
+	return nil
+}
+
+func (f *FakeStore) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("Close")
+	return nil
+}
@@ -0,0 +1,76 @@
+package fakestore
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dotenv213/umm/internal/userstore"
+)
+
+// Call log test
+func TestCalls(t *testing.T) {
+	store := New()
+	ctx := context.Background()
+
+	u := &userstore.User{Username: "fake", Email: "fake@test.com"}
+	if err := store.Create(ctx, u); err != nil {
+		t.Fatalf("Create failed : %v", err)
+	}
+	got, err := store.GetById(ctx, u.ID)
+	if err != nil || got.Username != "fake" {
+		t.Fatalf("GetById failed : %v %v", got, err)
+	}
+
+	want := []Call{
+		{Method: "Create", Args: []any{"fake", "fake@test.com"}},
+		{Method: "GetById", Args: []any{int64(1)}},
+	}
+	if calls := store.Calls(); !reflect.DeepEqual(calls, want) {
+		t.Errorf("Unexpected calls %v", calls)
+	}
+
+	store.Reset()
+	if calls := store.Calls(); len(calls) != 0 {
+		t.Errorf("Expected no calls after reset, got %v", calls)
+	}
+}
+
+// Forced create error test
+func TestFailNextCreate(t *testing.T) {
+	store := New()
+	ctx := context.Background()
+	boom := errors.New("boom")
+
+	store.FailNextCreate(boom)
+	if err := store.Create(ctx, &userstore.User{Username: "a", Email: "a@test.com"}); err != boom {
+		t.Errorf("Expected forced error, got %v", err)
+	}
+	if n, _ := store.Count(ctx); n != 0 {
+		t.Errorf("Expected nothing stored after a forced error, got %d", n)
+	}
+
+	// only the next create fails
+	if err := store.Create(ctx, &userstore.User{Username: "a", Email: "a@test.com"}); err != nil {
+		t.Errorf("Expected create to succeed, got %v", err)
+	}
+	if err := store.Create(ctx, &userstore.User{Username: "b", Email: "a@test.com"}); err != userstore.ErrDuplicateUser {
+		t.Errorf("Expected duplicate user, got %v", err)
+	}
+}
+
+// Methods that are not faked fail instead of panicking test
+func TestNotImplemented(t *testing.T) {
+	store := New()
+	ctx := context.Background()
+	if _, err := store.GetByUUID(ctx, "x"); !errors.Is(err, ErrNotImplemented) {
+		t.Errorf("Expected ErrNotImplemented from GetByUUID, got %v", err)
+	}
+	if _, err := store.Page(ctx, 10, 0); !errors.Is(err, ErrNotImplemented) {
+		t.Errorf("Expected ErrNotImplemented from Page, got %v", err)
+	}
+	if err := store.Truncate(ctx); !errors.Is(err, ErrNotImplemented) {
+		t.Errorf("Expected ErrNotImplemented from Truncate, got %v", err)
+	}
+}
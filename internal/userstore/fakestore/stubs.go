@@ -0,0 +1,242 @@
+package fakestore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/dotenv213/umm/internal/userstore"
+)
+
+// ErrNotImplemented is returned by every method FakeStore does not fake
+var ErrNotImplemented = errors.New("fakestore: not implemented")
+
+// the methods below are not faked, they fail with ErrNotImplemented
+
+func (f *FakeStore) BatchCreatePartial(ctx context.Context, users []*userstore.User) ([]userstore.CreateResult, error) {
+	return nil, ErrNotImplemented
+}
+
+func (f *FakeStore) GetByUUID(ctx context.Context, uuid string) (*userstore.User, error) {
+	return nil, ErrNotImplemented
+}
+
+func (f *FakeStore) Latest(ctx context.Context) (*userstore.User, error) {
+	return nil, ErrNotImplemented
+}
+
+func (f *FakeStore) GetByIdIncludingDeleted(ctx context.Context, id int64) (*userstore.User, error) {
+	return nil, ErrNotImplemented
+}
+
+func (f *FakeStore) GetMany(ctx context.Context, ids []int64) (map[int64]*userstore.User, error) {
+	return nil, ErrNotImplemented
+}
+
+func (f *FakeStore) GetInOrder(ctx context.Context, ids []int64) ([]*userstore.User, error) {
+	return nil, ErrNotImplemented
+}
+
+func (f *FakeStore) IsEmailAvailable(ctx context.Context, email string) (bool, error) {
+	return false, ErrNotImplemented
+}
+
+func (f *FakeStore) ExistingEmails(ctx context.Context, emails []string) (map[string]bool, error) {
+	return nil, ErrNotImplemented
+}
+
+func (f *FakeStore) GetOrCreateByEmail(ctx context.Context, user *userstore.User) (created bool, err error) {
+	return false, ErrNotImplemented
+}
+
+func (f *FakeStore) ListNames(ctx context.Context) ([]userstore.UserSummary, error) {
+	return nil, ErrNotImplemented
+}
+
+func (f *FakeStore) ListCreatedBy(ctx context.Context, creatorID int64) ([]userstore.User, error) {
+	return nil, ErrNotImplemented
+}
+
+func (f *FakeStore) ListDeleted(ctx context.Context) ([]userstore.User, error) {
+	return nil, ErrNotImplemented
+}
+
+func (f *FakeStore) FullTextSearch(ctx context.Context, query string) ([]userstore.User, error) {
+	return nil, ErrNotImplemented
+}
+
+func (f *FakeStore) Search(ctx context.Context, query string, limit, offset int) ([]userstore.User, int64, error) {
+	return nil, 0, ErrNotImplemented
+}
+
+func (f *FakeStore) Iterate(ctx context.Context, fn func(userstore.User) error) error {
+	return ErrNotImplemented
+}
+
+func (f *FakeStore) ListRecentlyUpdated(ctx context.Context, limit int) ([]userstore.User, error) {
+	return nil, ErrNotImplemented
+}
+
+func (f *FakeStore) ChangesSince(ctx context.Context, since time.Time, limit int) ([]userstore.User, error) {
+	return nil, ErrNotImplemented
+}
+
+func (f *FakeStore) Page(ctx context.Context, limit, offset int) (userstore.PageResult, error) {
+	return userstore.PageResult{}, ErrNotImplemented
+}
+
+func (f *FakeStore) Query(ctx context.Context, filter userstore.UserFilter) ([]userstore.User, error) {
+	return nil, ErrNotImplemented
+}
+
+func (f *FakeStore) ListByPrefix(ctx context.Context, prefix string) ([]userstore.User, error) {
+	return nil, ErrNotImplemented
+}
+
+func (f *FakeStore) QueryCount(ctx context.Context, filter userstore.UserFilter) (int64, error) {
+	return 0, ErrNotImplemented
+}
+
+func (f *FakeStore) CountByDomain(ctx context.Context) (map[string]int64, error) {
+	return nil, ErrNotImplemented
+}
+
+func (f *FakeStore) UpdateWithDiff(ctx context.Context, user *userstore.User) ([]string, error) {
+	return nil, ErrNotImplemented
+}
+
+func (f *FakeStore) SetMetadata(ctx context.Context, id int64, kv map[string]any) error {
+	return ErrNotImplemented
+}
+
+func (f *FakeStore) Patch(ctx context.Context, id int64, fields map[string]any) error {
+	return ErrNotImplemented
+}
+
+func (f *FakeStore) RenameUser(ctx context.Context, id int64, newUsername string) error {
+	return ErrNotImplemented
+}
+
+func (f *FakeStore) UsernameHistory(ctx context.Context, id int64) ([]string, error) {
+	return nil, ErrNotImplemented
+}
+
+func (f *FakeStore) SoftDelete(ctx context.Context, id int64) error {
+	return ErrNotImplemented
+}
+
+func (f *FakeStore) PurgeDeleted(ctx context.Context, olderThan time.Duration) (int64, error) {
+	return 0, ErrNotImplemented
+}
+
+func (f *FakeStore) UpdateRoleMany(ctx context.Context, ids []int64, role string) (int64, error) {
+	return 0, ErrNotImplemented
+}
+
+func (f *FakeStore) RequestEmailChange(ctx context.Context, id int64, newEmail string) (token string, err error) {
+	return "", ErrNotImplemented
+}
+
+func (f *FakeStore) ConfirmEmailChange(ctx context.Context, token string) error {
+	return ErrNotImplemented
+}
+
+func (f *FakeStore) RecordLogin(ctx context.Context, id int64) error {
+	return ErrNotImplemented
+}
+
+func (f *FakeStore) ListInactive(ctx context.Context, createdBefore time.Time) ([]userstore.User, error) {
+	return nil, ErrNotImplemented
+}
+
+func (f *FakeStore) MarkEmailVerified(ctx context.Context, id int64) error {
+	return ErrNotImplemented
+}
+
+func (f *FakeStore) ListUnverified(ctx context.Context) ([]userstore.User, error) {
+	return nil, ErrNotImplemented
+}
+
+func (f *FakeStore) Truncate(ctx context.Context) error {
+	return ErrNotImplemented
+}
+
+func (f *FakeStore) Renumber(ctx context.Context) error {
+	return ErrNotImplemented
+}
+
+func (f *FakeStore) MergeUsers(ctx context.Context, keepID, removeID int64) error {
+	return ErrNotImplemented
+}
+
+func (f *FakeStore) ListAudit(ctx context.Context, userID int64) ([]userstore.AuditEntry, error) {
+	return nil, ErrNotImplemented
+}
+
+func (f *FakeStore) Backup(ctx context.Context, destPath string) error {
+	return ErrNotImplemented
+}
+
+func (f *FakeStore) CloneTo(ctx context.Context, path string) (userstore.Store, error) {
+	return nil, ErrNotImplemented
+}
+
+func (f *FakeStore) Checkpoint(ctx context.Context) error {
+	return ErrNotImplemented
+}
+
+func (f *FakeStore) Verify(ctx context.Context) error {
+	return ErrNotImplemented
+}
+
+func (f *FakeStore) ExportUser(ctx context.Context, id int64, format string, w io.Writer) error {
+	return ErrNotImplemented
+}
+
+func (f *FakeStore) CheckSchema(ctx context.Context) error {
+	return ErrNotImplemented
+}
+
+func (f *FakeStore) FindOrphans(ctx context.Context) (userstore.OrphanReport, error) {
+	return userstore.OrphanReport{}, ErrNotImplemented
+}
+
+func (f *FakeStore) SchemaVersion(ctx context.Context) (int, error) {
+	return 0, ErrNotImplemented
+}
+
+func (f *FakeStore) ExportCSV(ctx context.Context, w io.Writer) (int, error) {
+	return 0, ErrNotImplemented
+}
+
+func (f *FakeStore) ExportJSONL(ctx context.Context, w io.Writer) error {
+	return ErrNotImplemented
+}
+
+func (f *FakeStore) DumpJSON(ctx context.Context, w io.Writer) error {
+	return ErrNotImplemented
+}
+
+func (f *FakeStore) LoadJSON(ctx context.Context, r io.Reader) error {
+	return ErrNotImplemented
+}
+
+func (f *FakeStore) ImportCSV(ctx context.Context, r io.Reader, skipDuplicates bool) (imported, skipped int, err error) {
+	return 0, 0, ErrNotImplemented
+}
+
+func (f *FakeStore) ValidateCSV(ctx context.Context, r io.Reader) (userstore.ValidationReport, error) {
+	return userstore.ValidationReport{}, ErrNotImplemented
+}
+
+// Config is the zero StoreConfig, a FakeStore has no options
+func (f *FakeStore) Config() userstore.StoreConfig {
+	return userstore.StoreConfig{}
+}
+
+// DBStats is zero, a FakeStore has no connection pool
+func (f *FakeStore) DBStats() sql.DBStats {
+	return sql.DBStats{}
+}
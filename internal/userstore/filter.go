@@ -0,0 +1,183 @@
+package userstore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// UserFilter selects users for Query and QueryCount
+// zero fields do not filter
+type UserFilter struct {
+	// Username matches users whose username contains it, ignoring case
+	Username string
+	// Role matches the role exactly
+	Role string
+	// CreatedAfter and CreatedBefore bound created_at, both exclusive
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	// Limit 0 returns up to the max page size, see WithMaxPageSize
+	// QueryCount ignores Limit and Offset
+	Limit  int
+	Offset int
+}
+
+// likeEscaper escapes LIKE wildcards so they match literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// where builds the WHERE clause shared by Query and QueryCount
+func (f UserFilter) where(tenant int64) (string, []any) {
+	conds := []string{"tenant_id = ?", "deleted_at IS NULL"}
+	args := []any{tenant}
+	if f.Username != "" {
+		conds = append(conds, `username LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+likeEscaper.Replace(f.Username)+"%")
+	}
+	if f.Role != "" {
+		conds = append(conds, "role = ?")
+		args = append(args, f.Role)
+	}
+	if !f.CreatedAfter.IsZero() {
+		conds = append(conds, "created_at > ?")
+		args = append(args, f.CreatedAfter.UTC())
+	}
+	if !f.CreatedBefore.IsZero() {
+		conds = append(conds, "created_at < ?")
+		args = append(args, f.CreatedBefore.UTC())
+	}
+	return ` WHERE ` + strings.Join(conds, " AND "), args
+}
+
+// Query returns the users matching f ordered by id
+func (s *sqlStore) Query(ctx context.Context, f UserFilter) ([]User, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	if f.Limit < 0 || f.Offset < 0 {
+		return nil, ErrInvalidPagination
+	}
+	// no limit means one full page
+	limit := f.Limit
+	if limit == 0 {
+		limit = s.cfg.maxPageSize
+	}
+	limit, err := s.pageLimit(limit)
+	if err != nil {
+		return nil, err
+	}
+	where, args := f.where(s.cfg.tenant)
+	query := `SELECT ` + userColumns + ` FROM users` + where + ` ORDER BY id LIMIT ? OFFSET ?`
+	args = append(args, limit, f.Offset)
+	rows, err := s.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users : %w", err)
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		u, err := s.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user : %w", err)
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration : %w", err)
+	}
+	return users, nil
+}
+
+// QueryCount returns how many users match f, ignoring Limit and Offset
+// so a filtered page can show the total number of results
+func (s *sqlStore) QueryCount(ctx context.Context, f UserFilter) (int64, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	where, args := f.where(s.cfg.tenant)
+	var n int64
+	if err := s.conn().QueryRowContext(ctx, `SELECT COUNT(*) FROM users`+where, args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count users : %w", err)
+	}
+	return n, nil
+}
+
+// ListByPrefix returns the users whose username starts with prefix
+// ignoring case and ordered by username, for alphabetical browsing
+// wildcards in prefix match literally
+func (s *sqlStore) ListByPrefix(ctx context.Context, prefix string) ([]User, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	query := `SELECT ` + userColumns + ` FROM users WHERE tenant_id = ? AND deleted_at IS NULL
+	AND username LIKE ? ESCAPE '\' ORDER BY username COLLATE NOCASE, id`
+	rows, err := s.conn().QueryContext(ctx, query, s.cfg.tenant, likeEscaper.Replace(prefix)+"%")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users by prefix : %w", err)
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		u, err := s.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user : %w", err)
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration : %w", err)
+	}
+	return users, nil
+}
+
+// Search returns one page of users whose username or email contains query
+// ignoring case and ordered by id, with the number of all matches
+// wildcards in query match literally, with email encryption only usernames are searched
+func (s *sqlStore) Search(ctx context.Context, query string, limit, offset int) ([]User, int64, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	if limit < 0 || offset < 0 {
+		return nil, 0, ErrInvalidPagination
+	}
+	// no limit means one full page, as in Query
+	if limit == 0 {
+		limit = s.cfg.maxPageSize
+	}
+	limit, err := s.pageLimit(limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	// email_lower is NULL for encrypted emails so ciphertext never matches
+	where := ` WHERE tenant_id = ? AND deleted_at IS NULL AND (username LIKE ? ESCAPE '\' OR email_lower LIKE ? ESCAPE '\')`
+	pattern := "%" + likeEscaper.Replace(query) + "%"
+	args := []any{s.cfg.tenant, pattern, pattern}
+	rows, err := s.conn().QueryContext(ctx, `SELECT `+userColumns+`, COUNT(*) OVER () FROM users`+where+` ORDER BY id LIMIT ? OFFSET ?`,
+		append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search users : %w", err)
+	}
+	defer rows.Close()
+
+	var users []User
+	var total int64
+	for rows.Next() {
+		u, err := s.scan(totalScanner{rows: rows, total: &total})
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan user : %w", err)
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error during rows iteration : %w", err)
+	}
+	rows.Close()
+
+	// an empty page has no row to carry the total
+	if len(users) == 0 {
+		if err := s.conn().QueryRowContext(ctx, `SELECT COUNT(*) FROM users`+where, args...).Scan(&total); err != nil {
+			return nil, 0, fmt.Errorf("failed to count users : %w", err)
+		}
+	}
+	return users, total, nil
+}
@@ -0,0 +1,121 @@
+package userstore
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// Filtered query and count test
+func TestQueryCount(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	store := storeWithOptions(t, WithClock(clock.Now))
+
+	for _, name := range []string{"alice_old", "bob_old", "alice_a", "alice_b", "carol", "alice_c"} {
+		if err := store.Create(ctx, &User{Username: name, Email: name + "@test.com"}); err != nil {
+			t.Fatalf("Create failed : %v", err)
+		}
+		clock.now = clock.now.Add(time.Hour)
+	}
+
+	// alice_a, alice_b and alice_c were created after the cutoff
+	f := UserFilter{Username: "ALICE", CreatedAfter: time.Date(2024, 1, 1, 13, 30, 0, 0, time.UTC), Limit: 2}
+	n, err := store.QueryCount(ctx, f)
+	if err != nil {
+		t.Fatalf("QueryCount failed : %v", err)
+	}
+	if n != 3 {
+		t.Errorf("Expected 3 matching users, got %d", n)
+	}
+
+	page, err := store.Query(ctx, f)
+	if err != nil {
+		t.Fatalf("Query failed : %v", err)
+	}
+	if len(page) != 2 || page[0].Username != "alice_a" || page[1].Username != "alice_b" {
+		t.Errorf("Unexpected page %v", page)
+	}
+
+	f.Limit = 0
+	all, _ := store.Query(ctx, f)
+	if int64(len(all)) != n {
+		t.Errorf("Expected count %d to match %d rows", n, len(all))
+	}
+
+	if n, _ := store.QueryCount(ctx, UserFilter{Username: "%"}); n != 0 {
+		t.Errorf("Expected wildcard to match literally, got %d", n)
+	}
+}
+
+// List by prefix test
+func TestListByPrefix(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"alice", "bob", "Alan", "a_l"} {
+		_ = store.Create(ctx, &User{Username: name, Email: name + "@test.com"})
+	}
+
+	users, err := store.ListByPrefix(ctx, "al")
+	if err != nil {
+		t.Fatalf("ListByPrefix failed : %v", err)
+	}
+	if len(users) != 2 || users[0].Username != "Alan" || users[1].Username != "alice" {
+		t.Errorf("Expected Alan and alice, got %+v", users)
+	}
+
+	if users, _ := store.ListByPrefix(ctx, "a_"); len(users) != 1 || users[0].Username != "a_l" {
+		t.Errorf("Expected only a_l for a literal underscore, got %+v", users)
+	}
+}
+
+// Paginated search test
+func TestSearch(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+	for _, u := range []*User{
+		{Username: "alice", Email: "alice@corp.com"},
+		{Username: "bob", Email: "bob@Corp.com"},
+		{Username: "corpbot", Email: "bot@test.com"},
+		{Username: "dave", Email: "dave@test.com"},
+		{Username: "100%_real", Email: "real@test.com"},
+	} {
+		if err := store.Create(ctx, u); err != nil {
+			t.Fatalf("Create failed : %v", err)
+		}
+	}
+
+	page, total, err := store.Search(ctx, "CORP", 2, 0)
+	if err != nil {
+		t.Fatalf("Search failed : %v", err)
+	}
+	if total != 3 || len(page) != 2 || page[0].Username != "alice" || page[1].Username != "bob" {
+		t.Errorf("Expected alice and bob of 3 matches, got %d %+v", total, page)
+	}
+	page, total, _ = store.Search(ctx, "corp", 2, 2)
+	if total != 3 || len(page) != 1 || page[0].Username != "corpbot" {
+		t.Errorf("Expected corpbot of 3 matches on the second page, got %d %+v", total, page)
+	}
+	// past the end still reports the total
+	page, total, _ = store.Search(ctx, "corp", 2, 10)
+	if total != 3 || len(page) != 0 {
+		t.Errorf("Expected no users of 3 matches, got %d %+v", total, page)
+	}
+	// no limit is one full page as in Query
+	page, total, err = store.Search(ctx, "corp", 0, 0)
+	if err != nil {
+		t.Fatalf("Search without limit failed : %v", err)
+	}
+	if total != 3 || len(page) != 3 {
+		t.Errorf("Expected all 3 matches without a limit, got %d %+v", total, page)
+	}
+
+	page, total, _ = store.Search(ctx, "%_", 10, 0)
+	if total != 1 || len(page) != 1 || page[0].Username != "100%_real" {
+		t.Errorf("Expected wildcards to match literally, got %d %+v", total, page)
+	}
+	if _, _, err := store.Search(ctx, "x", -1, 0); err != ErrInvalidPagination {
+		t.Errorf("Expected ErrInvalidPagination, got %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package userstore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ftsSchema keeps users_fts in sync with users through triggers
+// users_fts is contentless, it only holds the index
+// so rows are removed with the special 'delete' insert
+var ftsSchema = []string{
+	`CREATE VIRTUAL TABLE users_fts USING fts5(username, email, content='');`,
+	`CREATE TRIGGER users_fts_insert AFTER INSERT ON users BEGIN
+		INSERT INTO users_fts (rowid, username, email) VALUES (new.id, new.username, new.email);
+	END;`,
+	`CREATE TRIGGER users_fts_delete AFTER DELETE ON users BEGIN
+		INSERT INTO users_fts (users_fts, rowid, username, email) VALUES ('delete', old.id, old.username, old.email);
+	END;`,
+	`CREATE TRIGGER users_fts_update AFTER UPDATE OF id, username, email ON users BEGIN
+		INSERT INTO users_fts (users_fts, rowid, username, email) VALUES ('delete', old.id, old.username, old.email);
+		INSERT INTO users_fts (rowid, username, email) VALUES (new.id, new.username, new.email);
+	END;`,
+	// index the users that existed before search was turned on
+	`INSERT INTO users_fts (rowid, username, email) SELECT id, username, email FROM users;`,
+}
+
+// setupFullText creates the fts table and triggers once
+// the sqlite build must include fts5 (go build -tags sqlite_fts5)
+func (s *sqlStore) setupFullText() error {
+	var available bool
+	if err := s.db.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&available); err != nil {
+		return fmt.Errorf("failed to check fts5 support : %w", err)
+	}
+	if !available {
+		return fmt.Errorf("%w : sqlite was built without fts5, build with -tags sqlite_fts5", ErrFullTextUnavailable)
+	}
+
+	// rebuilding users drops the triggers but keeps users_fts
+	// then the index is stale and is built again from scratch
+	var tableExists, triggersExist bool
+	query := `SELECT
+		EXISTS(SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'users_fts'),
+		EXISTS(SELECT 1 FROM sqlite_master WHERE type = 'trigger' AND name = 'users_fts_insert')`
+	if err := s.db.QueryRow(query).Scan(&tableExists, &triggersExist); err != nil {
+		return fmt.Errorf("failed to read schema : %w", err)
+	}
+	if tableExists && triggersExist {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("Failed to begin transctions : %w", err)
+	}
+	defer tx.Rollback()
+	if tableExists {
+		if _, err := tx.Exec(`DROP TABLE users_fts`); err != nil {
+			return fmt.Errorf("failed to drop stale full-text index : %w", err)
+		}
+	}
+	for _, q := range ftsSchema {
+		if _, err := tx.Exec(q); err != nil {
+			return fmt.Errorf("failed to set up full-text search : %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ftsQuery quotes every word of the input as an fts5 string
+// so user input is matched as text and never parsed as query syntax
+func ftsQuery(input string) string {
+	words := strings.Fields(input)
+	for i, w := range words {
+		words[i] = `"` + strings.ReplaceAll(w, `"`, `""`) + `"`
+	}
+	return strings.Join(words, " ")
+}
+
+// FullTextSearch returns users whose username or email contain every word
+// of query, best match first
+func (s *sqlStore) FullTextSearch(ctx context.Context, query string) ([]User, error) {
+	if !s.cfg.fullText {
+		return nil, ErrFullTextUnavailable
+	}
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+
+	match := ftsQuery(query)
+	if match == "" {
+		return nil, nil
+	}
+	q := `SELECT ` + userColumns + ` FROM users
+	JOIN (SELECT rowid, rank FROM users_fts WHERE users_fts MATCH ?) m ON users.id = m.rowid
+	WHERE users.tenant_id = ? AND users.deleted_at IS NULL
+	ORDER BY m.rank`
+	rows, err := s.conn().QueryContext(ctx, q, match, s.cfg.tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users : %w", err)
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		u, err := s.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user : %w", err)
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration : %w", err)
+	}
+	return users, nil
+}
@@ -0,0 +1,70 @@
+package userstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// ftsStore skips when sqlite was built without fts5
+func ftsStore(t *testing.T) Store {
+	t.Helper()
+
+	store, err := NewDb(":memory:", WithFullTextSearch(true))
+	if errors.Is(err, ErrFullTextUnavailable) {
+		t.Skip("sqlite built without fts5, run with -tags sqlite_fts5")
+	}
+	if err != nil {
+		t.Fatalf("Create DB: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+	return store
+}
+
+// Full-text search test
+func TestFullTextSearch(t *testing.T) {
+	store := ftsStore(t)
+	ctx := context.Background()
+
+	_ = store.Create(ctx, &User{Username: "bob", Email: "alice.fan@test.com"})
+	_ = store.Create(ctx, &User{Username: "alice", Email: "alice@test.com"})
+	_ = store.Create(ctx, &User{Username: "carol", Email: "carol@test.com"})
+
+	users, err := store.FullTextSearch(ctx, "alice")
+	if err != nil {
+		t.Fatalf("Search failed : %v", err)
+	}
+	if len(users) != 2 {
+		t.Fatalf("Expected 2 matches, got %d", len(users))
+	}
+	// matching both columns ranks above matching only the email
+	if users[0].Username != "alice" {
+		t.Errorf("Expected alice first, got %s", users[0].Username)
+	}
+
+	// the index follows updates and deletes
+	users[0].Username = "alicia"
+	_ = store.Update(ctx, &users[0])
+	_ = store.Delete(ctx, users[1].ID)
+	users, _ = store.FullTextSearch(ctx, "alicia")
+	if len(users) != 1 {
+		t.Errorf("Expected 1 match after update, got %d", len(users))
+	}
+
+	// query syntax is treated as text
+	if _, err := store.FullTextSearch(ctx, `"unbalanced AND (`); err != nil {
+		t.Errorf("Expected odd input to be searched as text, got %v", err)
+	}
+}
+
+// Search without the option test
+func TestFullTextSearchDisabled(t *testing.T) {
+	store := StoreTest(t)
+
+	if _, err := store.FullTextSearch(context.Background(), "x"); err != ErrFullTextUnavailable {
+		t.Fatalf("Expected full-text unavailable error, got %v", err)
+	}
+}
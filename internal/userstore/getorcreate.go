@@ -0,0 +1,36 @@
+package userstore
+
+import (
+	"context"
+	"errors"
+)
+
+// GetOrCreateByEmail fills user with the stored user of the same email
+// or creates user when there is none, created reports which one happened
+// a concurrent create of the same email loses the unique check here
+// and is resolved by fetching the row the other caller wrote
+func (s *sqlStore) GetOrCreateByEmail(ctx context.Context, user *User) (created bool, err error) {
+	existing, err := s.GetByEmail(ctx, user.Email)
+	if err == nil {
+		*user = *existing
+		return false, nil
+	}
+	if !errors.Is(err, ErrUserNotFound) {
+		return false, err
+	}
+
+	err = s.Create(ctx, user)
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, ErrDuplicateUser) {
+		return false, err
+	}
+	// the duplicate may be the username, then there is nothing to fetch
+	existing, ferr := s.GetByEmail(ctx, user.Email)
+	if ferr != nil {
+		return false, err
+	}
+	*user = *existing
+	return false, nil
+}
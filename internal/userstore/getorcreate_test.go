@@ -0,0 +1,67 @@
+package userstore
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// Get or create test
+func TestGetOrCreateByEmail(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	u := &User{Username: "goc", Email: "goc@test.com"}
+	created, err := store.GetOrCreateByEmail(ctx, u)
+	if err != nil || !created {
+		t.Fatalf("Expected create, got %v %v", created, err)
+	}
+
+	again := &User{Username: "other", Email: "goc@test.com"}
+	created, err = store.GetOrCreateByEmail(ctx, again)
+	if err != nil || created {
+		t.Fatalf("Expected fetch, got %v %v", created, err)
+	}
+	if again.ID != u.ID || again.Username != "goc" {
+		t.Errorf("Expected stored user %d, got %d %q", u.ID, again.ID, again.Username)
+	}
+}
+
+// Concurrent get or create test
+func TestGetOrCreateByEmailConcurrent(t *testing.T) {
+	store := storeAt(t, filepath.Join(t.TempDir(), "goc.db"))
+	ctx := context.Background()
+
+	const callers = 8
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	ids := make(map[int64]bool)
+	createdCount := 0
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u := &User{Username: "race", Email: "race@test.com"}
+			created, err := store.GetOrCreateByEmail(ctx, u)
+			if err != nil {
+				t.Errorf("GetOrCreateByEmail failed : %v", err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			ids[u.ID] = true
+			if created {
+				createdCount++
+			}
+		}()
+	}
+	wg.Wait()
+
+	if createdCount != 1 || len(ids) != 1 {
+		t.Errorf("Expected one create and one id, got %d creates and ids %v", createdCount, ids)
+	}
+	if n, _ := store.Count(ctx); n != 1 {
+		t.Errorf("Expected 1 row, got %d", n)
+	}
+}
@@ -0,0 +1,25 @@
+package userstore
+
+import (
+	"context"
+	"fmt"
+)
+
+// applyIDOffset raises the users sequence so the next id is at least the offset
+// a sequence that is already past it is left alone
+func (s *sqlStore) applyIDOffset(ctx context.Context, q querier) error {
+	if s.cfg.idOffset == nil {
+		return nil
+	}
+	seq := *s.cfg.idOffset - 1
+	queries := []string{
+		`DELETE FROM sqlite_sequence WHERE name = 'users' AND seq < ?`,
+		`INSERT INTO sqlite_sequence (name, seq) SELECT 'users', ? WHERE NOT EXISTS (SELECT 1 FROM sqlite_sequence WHERE name = 'users')`,
+	}
+	for _, query := range queries {
+		if _, err := q.ExecContext(ctx, query, seq); err != nil {
+			return fmt.Errorf("failed to set id offset : %w", err)
+		}
+	}
+	return nil
+}
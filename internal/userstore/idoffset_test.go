@@ -0,0 +1,33 @@
+package userstore
+
+import (
+	"context"
+	"testing"
+)
+
+// Id offset test
+func TestIDOffset(t *testing.T) {
+	store := storeWithOptions(t, WithIDOffset(1000))
+	ctx := context.Background()
+
+	u := &User{Username: "shard", Email: "shard@test.com"}
+	if err := store.Create(ctx, u); err != nil {
+		t.Fatalf("Create failed : %v", err)
+	}
+	if u.ID != 1000 {
+		t.Errorf("Expected id 1000, got %d", u.ID)
+	}
+
+	if err := store.Truncate(ctx); err != nil {
+		t.Fatalf("Truncate failed : %v", err)
+	}
+	u = &User{Username: "again", Email: "again@test.com"}
+	_ = store.Create(ctx, u)
+	if u.ID != 1000 {
+		t.Errorf("Expected id 1000 after truncate, got %d", u.ID)
+	}
+
+	if _, err := NewDb(":memory:", WithIDOffset(0)); err != ErrInvalidIDOffset {
+		t.Errorf("Expected ErrInvalidIDOffset, got %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package userstore
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// malicious strings that would break a concatenated query
+var injectionInputs = []string{
+	`"); DROP TABLE users; --`,
+	`'; DROP TABLE users; --`,
+	`' OR '1'='1`,
+	`x@test.com' OR 1=1 --`,
+}
+
+// SQL injection regression test
+// every string input must be bound as a value, never run as sql
+func TestInjectionSafety(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+	_ = store.Create(ctx, &User{Username: "victim", Email: "victim@test.com"})
+
+	for i, input := range injectionInputs {
+		// a malformed email is rejected before any query runs
+		if err := store.Create(ctx, &User{Username: input, Email: input}); err != ErrInvalidEmail {
+			t.Fatalf("Create with email %q expected invalid email, got %v", input, err)
+		}
+		u := &User{Username: input, Email: fmt.Sprintf("user%d@test.com", i)}
+		if err := store.Create(ctx, u); err != nil {
+			t.Fatalf("Create with %q failed : %v", input, err)
+		}
+		got, err := store.GetById(ctx, u.ID)
+		if err != nil {
+			t.Fatalf("GetById failed : %v", err)
+		}
+		if got.Username != input || got.Email != u.Email {
+			t.Errorf("Expected literal value %q, got %+v", input, got)
+		}
+
+		found, err := store.ExistsByEmail(ctx, input)
+		if err != nil || found {
+			t.Errorf("ExistsByEmail(%q) = %v %v", input, found, err)
+		}
+		found, err = store.ExistsByUsername(ctx, input)
+		if err != nil || !found {
+			t.Errorf("ExistsByUsername(%q) = %v %v", input, found, err)
+		}
+		if _, err := store.GetByUUID(ctx, input); err != ErrUserNotFound {
+			t.Errorf("GetByUUID(%q) expected not found, got %v", input, err)
+		}
+		if err := store.ConfirmEmailChange(ctx, input); err != ErrInvalidToken {
+			t.Errorf("ConfirmEmailChange(%q) expected invalid token, got %v", input, err)
+		}
+
+		// the table is still there with exactly the rows created so far
+		n, err := store.Count(ctx)
+		if err != nil {
+			t.Fatalf("Count after %q failed : %v", input, err)
+		}
+		if n != int64(i+2) {
+			t.Fatalf("Expected %d users, got %d", i+2, n)
+		}
+	}
+
+	if _, err := store.GetById(ctx, 1); err != nil {
+		t.Errorf("Expected the first user to be untouched : %v", err)
+	}
+}
+
+// SQL injection through the search query test
+func TestInjectionSafetySearch(t *testing.T) {
+	store := ftsStore(t)
+	ctx := context.Background()
+	_ = store.Create(ctx, &User{Username: "victim", Email: "victim@test.com"})
+
+	for _, input := range injectionInputs {
+		if _, err := store.FullTextSearch(ctx, input); err != nil {
+			t.Errorf("FullTextSearch(%q) failed : %v", input, err)
+		}
+	}
+	if n, err := store.Count(ctx); err != nil || n != 1 {
+		t.Fatalf("Expected users table intact, got %d %v", n, err)
+	}
+}
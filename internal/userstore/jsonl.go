@@ -0,0 +1,22 @@
+package userstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ExportJSONL writes every user as one json object per line
+// rows are streamed so memory stays flat for large tables
+func (s *sqlStore) ExportJSONL(ctx context.Context, w io.Writer) error {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	enc := json.NewEncoder(w)
+	return s.Iterate(ctx, func(u User) error {
+		if err := enc.Encode(u); err != nil {
+			return fmt.Errorf("failed to write json line : %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,36 @@
+package userstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// Export json lines test
+func TestExportJSONL(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+	_ = store.Create(ctx, &User{Username: "j1", Email: "j1@test.com"})
+	_ = store.Create(ctx, &User{Username: "j2", Email: "j2@test.com"})
+
+	var buf bytes.Buffer
+	if err := store.ExportJSONL(ctx, &buf); err != nil {
+		t.Fatalf("Export failed : %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+	for i, line := range lines {
+		var u User
+		if err := json.Unmarshal([]byte(line), &u); err != nil {
+			t.Fatalf("Unmarshal line %d failed : %v", i, err)
+		}
+		if u.ID == 0 || u.Username == "" || u.Email == "" {
+			t.Errorf("Line %d is missing fields : %+v", i, u)
+		}
+	}
+}
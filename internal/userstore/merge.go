@@ -0,0 +1,54 @@
+package userstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// MergeUsers folds removeID into keepID in one transaction
+// audit entries, username history and created_by references move to the kept user,
+// pending email changes of the removed user are dropped
+// and then the removed user is deleted
+func (s *sqlStore) MergeUsers(ctx context.Context, keepID, removeID int64) error {
+	if s.cfg.readOnly {
+		return ErrReadOnly
+	}
+	if keepID == removeID {
+		return ErrMergeSameUser
+	}
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := s.readUserTx(ctx, tx, keepID); err != nil {
+			return err
+		}
+		removed, err := s.readUserTx(ctx, tx, removeID)
+		if err != nil {
+			return err
+		}
+
+		queries := []struct {
+			query string
+			args  []any
+		}{
+			{`UPDATE audit_log SET user_id = ? WHERE user_id = ?`, []any{keepID, removeID}},
+			// the kept user must not end up as its own creator
+			{`UPDATE users SET created_by = CASE WHEN id = ? THEN NULL ELSE ? END WHERE created_by = ?`, []any{keepID, keepID, removeID}},
+			{`DELETE FROM email_changes WHERE user_id = ?`, []any{removeID}},
+			// old links to the removed user lead to the kept one
+			{`UPDATE username_history SET user_id = ? WHERE user_id = ?`, []any{keepID, removeID}},
+			{`DELETE FROM users WHERE id = ?`, []any{removeID}},
+		}
+		for _, q := range queries {
+			if _, err := tx.ExecContext(ctx, q.query, q.args...); err != nil {
+				return fmt.Errorf("failed to merge users : %w", err)
+			}
+		}
+		if err := s.tombstone(ctx, tx, removeID); err != nil {
+			return err
+		}
+		return s.writeAudit(ctx, tx, AuditActionDelete, removed)
+	})
+}
@@ -0,0 +1,56 @@
+package userstore
+
+import (
+	"context"
+	"testing"
+)
+
+// Merge moves dependents test
+func TestMergeUsers(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	keep := &User{Username: "keep", Email: "keep@test.com"}
+	remove := &User{Username: "remove", Email: "remove@test.com"}
+	_ = store.Create(ctx, keep)
+	_ = store.Create(ctx, remove)
+	child := &User{Username: "child", Email: "child@test.com", CreatedBy: &remove.ID}
+	_ = store.Create(ctx, child)
+
+	if err := store.MergeUsers(ctx, keep.ID, remove.ID); err != nil {
+		t.Fatalf("Merge failed : %v", err)
+	}
+
+	if _, err := store.GetById(ctx, remove.ID); err != ErrUserNotFound {
+		t.Errorf("Expected removed user to be gone, got %v", err)
+	}
+	got, _ := store.GetById(ctx, child.ID)
+	if got.CreatedBy == nil || *got.CreatedBy != keep.ID {
+		t.Errorf("Expected child to point at kept user, got %v", got.CreatedBy)
+	}
+
+	// keep's create entry plus remove's create entry moved over
+	entries, _ := store.ListAudit(ctx, keep.ID)
+	if len(entries) != 2 {
+		t.Errorf("Expected 2 audit entries on kept user, got %d", len(entries))
+	}
+}
+
+// Merge with missing user test
+func TestMergeUsersNotFound(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	u := &User{Username: "u", Email: "u@test.com"}
+	_ = store.Create(ctx, u)
+
+	if err := store.MergeUsers(ctx, u.ID, 999); err != ErrUserNotFound {
+		t.Errorf("Expected user not found, got %v", err)
+	}
+	if err := store.MergeUsers(ctx, 999, u.ID); err != ErrUserNotFound {
+		t.Errorf("Expected user not found, got %v", err)
+	}
+	if err := store.MergeUsers(ctx, u.ID, u.ID); err != ErrMergeSameUser {
+		t.Errorf("Expected same user error, got %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package userstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// encodeMetadata is the metadata column value for m, NULL when m is empty
+// json sorts map keys so equal maps encode the same
+func encodeMetadata(m map[string]any) (any, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode metadata : %w", err)
+	}
+	return string(b), nil
+}
+
+// SetMetadata merges kv into the stored metadata of the user
+// keys in kv replace the stored ones, a nil value removes the key
+// and other stored keys are kept
+func (s *sqlStore) SetMetadata(ctx context.Context, id int64, kv map[string]any) error {
+	if s.cfg.readOnly {
+		return ErrReadOnly
+	}
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		current, err := s.readUserTx(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if current.DeletedAt != nil {
+			return ErrUserNotFound
+		}
+
+		merged := current.Metadata
+		if merged == nil {
+			merged = make(map[string]any, len(kv))
+		}
+		for k, v := range kv {
+			if v == nil {
+				delete(merged, k)
+				continue
+			}
+			merged[k] = v
+		}
+		metadata, err := encodeMetadata(merged)
+		if err != nil {
+			return err
+		}
+
+		query := `UPDATE users SET metadata = ?, updated_at = ? WHERE id = ?`
+		if _, err := tx.ExecContext(ctx, query, metadata, s.now().UTC(), id); err != nil {
+			return fmt.Errorf("failed to set metadata : %w", err)
+		}
+
+		stored, err := s.readUserTx(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		return s.writeAudit(ctx, tx, AuditActionUpdate, stored)
+	})
+}
@@ -0,0 +1,71 @@
+package userstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// Metadata test
+func TestMetadata(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	u := &User{Username: "meta", Email: "meta@test.com", Metadata: map[string]any{"team": "core"}}
+	if err := store.Create(ctx, u); err != nil {
+		t.Fatalf("Create failed : %v", err)
+	}
+
+	err := store.SetMetadata(ctx, u.ID, map[string]any{"level": 3, "team": "infra"})
+	if err != nil {
+		t.Fatalf("SetMetadata failed : %v", err)
+	}
+	if err := store.SetMetadata(ctx, u.ID, map[string]any{"beta": true}); err != nil {
+		t.Fatalf("SetMetadata failed : %v", err)
+	}
+
+	got, err := store.GetById(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("GetById failed : %v", err)
+	}
+	if len(got.Metadata) != 3 || got.Metadata["team"] != "infra" || got.Metadata["level"] != 3.0 || got.Metadata["beta"] != true {
+		t.Errorf("Unexpected merged metadata %v", got.Metadata)
+	}
+
+	// a nil value removes the key
+	_ = store.SetMetadata(ctx, u.ID, map[string]any{"beta": nil})
+	got, _ = store.GetById(ctx, u.ID)
+	if _, ok := got.Metadata["beta"]; ok {
+		t.Errorf("Expected beta removed, got %v", got.Metadata)
+	}
+
+	// Update writes the metadata of the user as given
+	got.Metadata = map[string]any{"team": "ops"}
+	changed, err := store.UpdateWithDiff(ctx, got)
+	if err != nil {
+		t.Fatalf("UpdateWithDiff failed : %v", err)
+	}
+	if len(changed) != 1 || changed[0] != "metadata" {
+		t.Errorf("Expected [metadata] changed, got %v", changed)
+	}
+
+	if err := store.SetMetadata(ctx, 999, map[string]any{"a": 1}); err != ErrUserNotFound {
+		t.Errorf("Expected user not found, got %v", err)
+	}
+}
+
+// Invalid stored metadata test
+func TestMetadataInvalid(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	u := &User{Username: "broken", Email: "broken@test.com"}
+	_ = store.Create(ctx, u)
+	if _, err := store.(*sqlStore).db.Exec(`UPDATE users SET metadata = '{not json' WHERE id = ?`, u.ID); err != nil {
+		t.Fatalf("Corrupt metadata failed : %v", err)
+	}
+
+	if _, err := store.GetById(ctx, u.ID); !errors.Is(err, ErrInvalidMetadata) {
+		t.Errorf("Expected ErrInvalidMetadata, got %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package userstore
+
+import (
+	"context"
+	"fmt"
+)
+
+// migrations change tables that already exist in older databases
+// migrations[i] is schema version i+1
+// never edit or reorder a released entry, append a new one
+var migrations = []string{
+	// 1: when the user last logged in, NULL means never
+	`ALTER TABLE users ADD COLUMN last_login_at DATETIME;`,
+	// 2: the user that created this account, NULL for self signup
+	`ALTER TABLE users ADD COLUMN created_by INTEGER REFERENCES users(id);
+	CREATE INDEX idx_users_created_by ON users (created_by);`,
+	// 3: public uuid, only filled in with the UUID id strategy
+	`ALTER TABLE users ADD COLUMN uuid TEXT;
+	CREATE UNIQUE INDEX idx_users_uuid ON users (uuid);`,
+	// 4: legacy rows inserted without created_at
+	`UPDATE users SET created_at = CURRENT_TIMESTAMP WHERE created_at IS NULL;`,
+	// 5: what the user is allowed to do, see validRoles
+	`ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user';`,
+	// 6: soft delete marker, NULL means the user is active
+	`ALTER TABLE users ADD COLUMN deleted_at DATETIME;`,
+	// 7: whether the user confirmed owning the email
+	`ALTER TABLE users ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT 0;`,
+	// 8: who made an audited change, NULL for the system
+	// not a foreign key for the same reason as audit_log.user_id
+	`ALTER TABLE audit_log ADD COLUMN actor_id INTEGER;`,
+	// 9: email without aliases, only filled in WithEmailCanonicalization
+	`ALTER TABLE users ADD COLUMN canonical_email TEXT;
+	CREATE UNIQUE INDEX idx_users_canonical_email ON users (canonical_email);`,
+	// 10: when the user was last changed, NULL for never
+	`ALTER TABLE users ADD COLUMN updated_at DATETIME;`,
+	// 11: keyed hash of the email, only filled in WithEmailEncryption
+	`ALTER TABLE users ADD COLUMN email_hash TEXT;
+	CREATE UNIQUE INDEX idx_users_email_hash ON users (email_hash);`,
+	// 12: tenant of the user, see WithTenant
+	// username and email were unique inline so the table is rebuilt
+	// to make them unique per tenant, the id sequence is carried over
+	`CREATE TABLE users_new (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		tenant_id INTEGER NOT NULL DEFAULT 0,
+		username TEXT NOT NULL,
+		email TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		last_login_at DATETIME,
+		created_by INTEGER REFERENCES users(id),
+		uuid TEXT,
+		role TEXT NOT NULL DEFAULT 'user',
+		deleted_at DATETIME,
+		email_verified BOOLEAN NOT NULL DEFAULT 0,
+		canonical_email TEXT,
+		updated_at DATETIME,
+		email_hash TEXT
+	);
+	INSERT INTO users_new (id, username, email, created_at, last_login_at, created_by, uuid, role, deleted_at, email_verified, canonical_email, updated_at, email_hash)
+	SELECT id, username, email, created_at, last_login_at, created_by, uuid, role, deleted_at, email_verified, canonical_email, updated_at, email_hash FROM users;
+	DELETE FROM sqlite_sequence WHERE name = 'users_new';
+	UPDATE sqlite_sequence SET name = 'users_new' WHERE name = 'users';
+	DROP TABLE users;
+	ALTER TABLE users_new RENAME TO users;
+	CREATE UNIQUE INDEX idx_users_tenant_username ON users (tenant_id, username);
+	CREATE UNIQUE INDEX idx_users_tenant_email ON users (tenant_id, email);
+	CREATE UNIQUE INDEX idx_users_tenant_canonical_email ON users (tenant_id, canonical_email);
+	CREATE UNIQUE INDEX idx_users_tenant_email_hash ON users (tenant_id, email_hash);
+	CREATE UNIQUE INDEX idx_users_uuid ON users (uuid);
+	CREATE INDEX idx_users_created_by ON users (created_by);`,
+	// 13: json object of deployment specific fields, NULL for none
+	`ALTER TABLE users ADD COLUMN metadata TEXT;`,
+	// 14: lowercased email, unique per tenant so emails ignore case
+	// encrypted emails have no @ and keep NULL since email_hash covers them
+	// of older rows that differ only in case the lowest id gets the value
+	`ALTER TABLE users ADD COLUMN email_lower TEXT;
+	UPDATE users SET email_lower = lower(trim(email)) WHERE email LIKE '%@%' AND NOT EXISTS (
+		SELECT 1 FROM users o WHERE o.tenant_id = users.tenant_id AND lower(trim(o.email)) = lower(trim(users.email)) AND o.id < users.id);
+	CREATE UNIQUE INDEX idx_users_tenant_email_lower ON users (tenant_id, email_lower);`,
+	// 15: usernames a user had before RenameUser, for redirecting old links
+	`CREATE TABLE username_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		username TEXT NOT NULL,
+		changed_at DATETIME NOT NULL
+	);
+	CREATE INDEX idx_username_history_user_id ON username_history (user_id);`,
+	// 16: ids of hard deleted users without anything personal, see WithDeletionTombstones
+	// not a foreign key since the user is gone
+	`CREATE TABLE deletions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		deleted_at DATETIME NOT NULL
+	);`,
+	// 17: users without an email, see WithEmailRequired
+	// leave empty emails out of the unique email index
+	`DROP INDEX idx_users_tenant_email;
+	CREATE UNIQUE INDEX idx_users_tenant_email ON users (tenant_id, email) WHERE email <> '';`,
+	// 18: name shown to people, NULL shows the username, not unique
+	`ALTER TABLE users ADD COLUMN display_name TEXT;`,
+	// 19: tenant of an audit entry so ListAudit stays in its tenant
+	// entries of users deleted before this migration keep tenant 0
+	`ALTER TABLE audit_log ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 0;
+	UPDATE audit_log SET tenant_id = (SELECT tenant_id FROM users WHERE users.id = audit_log.user_id)
+	WHERE EXISTS (SELECT 1 FROM users WHERE users.id = audit_log.user_id);
+	CREATE INDEX idx_audit_log_tenant_user_id ON audit_log (tenant_id, user_id);`,
+}
+
+// applyMigrations runs every migration newer than the recorded version
+// each one commits together with its schema_migrations row
+func (s *sqlStore) applyMigrations() error {
+	var current int
+	if err := s.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version : %w", err)
+	}
+	if current >= len(migrations) {
+		return nil
+	}
+
+	// foreign keys are off while migrating so rebuilding a table
+	// does not cascade the drop of the old one into email_changes
+	// the pragma is per connection and a no-op inside a transaction
+	ctx := context.Background()
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection : %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+		return fmt.Errorf("failed to disable foreign keys : %w", err)
+	}
+	defer conn.ExecContext(ctx, `PRAGMA foreign_keys = ON`)
+
+	for v := current + 1; v <= len(migrations); v++ {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("Failed to begin transctions : %w", err)
+		}
+		if _, err := tx.Exec(migrations[v-1]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply migration %d : %w", v, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version) VALUES (?)`, v); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d : %w", v, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d : %w", v, err)
+		}
+	}
+	return nil
+}
+
+// SchemaVersion returns the newest applied migration
+// a database without schema_migrations reports 0
+func (s *sqlStore) SchemaVersion(ctx context.Context) (int, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	var tables int
+	query := `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'`
+	if err := s.conn().QueryRowContext(ctx, query).Scan(&tables); err != nil {
+		return 0, fmt.Errorf("failed to read schema version : %w", err)
+	}
+	if tables == 0 {
+		return 0, nil
+	}
+
+	var version int
+	query = `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`
+	if err := s.conn().QueryRowContext(ctx, query).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version : %w", err)
+	}
+	return version, nil
+}
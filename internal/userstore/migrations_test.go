@@ -0,0 +1,162 @@
+package userstore
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+// Upgrade of a database made before migrations test
+func TestMigrateOldDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.db")
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`
+	CREATE TABLE users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username TEXT NOT NULL UNIQUE,
+		email TEXT NOT NULL UNIQUE,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	INSERT INTO users (username, email) VALUES ('old', 'old@test.com');`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	store := storeAt(t, path)
+	got, err := store.GetById(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("GetById after migration failed : %v", err)
+	}
+	if got.Username != "old" || got.LastLoginAt != nil {
+		t.Errorf("Unexpected migrated user %+v", got)
+	}
+
+	// opening again must not re-run migrations
+	_ = store.Close()
+	storeAt(t, path)
+}
+
+// NULL created_at test
+func TestNullCreatedAt(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	db := store.(*sqlStore).db
+	if _, err := db.Exec(`INSERT INTO users (username, email, created_at) VALUES ('legacy', 'legacy@test.com', NULL)`); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.GetById(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetById with NULL created_at failed : %v", err)
+	}
+	if !got.CreatedAt.IsZero() {
+		t.Errorf("Expected zero created_at, got %v", got.CreatedAt)
+	}
+	if _, err := store.ListAll(ctx); err != nil {
+		t.Fatalf("ListAll with NULL created_at failed : %v", err)
+	}
+
+}
+
+// Backfill of NULL created_at test
+func TestBackfillCreatedAt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`
+	CREATE TABLE users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username TEXT NOT NULL UNIQUE,
+		email TEXT NOT NULL UNIQUE,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	INSERT INTO users (username, email, created_at) VALUES ('legacy', 'legacy@test.com', NULL);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	store := storeAt(t, path)
+	got, err := store.GetById(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("GetById failed : %v", err)
+	}
+	if got.CreatedAt.IsZero() {
+		t.Error("Expected created_at to be backfilled")
+	}
+}
+
+// Schema version test
+func TestSchemaVersion(t *testing.T) {
+	ctx := context.Background()
+	v, err := StoreTest(t).SchemaVersion(ctx)
+	if err != nil {
+		t.Fatalf("SchemaVersion failed : %v", err)
+	}
+	if v != len(migrations) {
+		t.Errorf("Expected version %d, got %d", len(migrations), v)
+	}
+
+	// a legacy database opened read-only never gets migrated
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`PRAGMA journal_mode = WAL;
+	CREATE TABLE users (id INTEGER PRIMARY KEY, username TEXT, email TEXT, created_at DATETIME);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	v, err = storeAt(t, path, WithReadOnly(true)).SchemaVersion(ctx)
+	if err != nil {
+		t.Fatalf("SchemaVersion failed : %v", err)
+	}
+	if v != 0 {
+		t.Errorf("Expected version 0, got %d", v)
+	}
+}
+
+// Backfill of email_lower test
+func TestBackfillEmailLower(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`
+	CREATE TABLE users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username TEXT NOT NULL UNIQUE,
+		email TEXT NOT NULL UNIQUE,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	INSERT INTO users (username, email) VALUES ('first', 'Same@test.com'), ('second', 'same@TEST.com');`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	// the older row keeps the address when two differ only in case
+	store := storeAt(t, path)
+	got, err := store.GetByEmail(context.Background(), "same@test.com")
+	if err != nil {
+		t.Fatalf("GetByEmail failed : %v", err)
+	}
+	if got.Username != "first" {
+		t.Errorf("Expected first user, got %s", got.Username)
+	}
+}
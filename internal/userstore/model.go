@@ -1,6 +1,9 @@
 package userstore
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // User data across the module
 type User struct {
@@ -8,4 +11,97 @@ type User struct {
 	Username  string    `json:"username"`
 	Email     string    `json:"email"`
 	CreatedAt time.Time `json:"created_at"`
+	// nil until the first recorded login
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	// id of the user that created this one, nil for self signup
+	CreatedBy *int64 `json:"created_by,omitempty"`
+	// public id, set when the store uses the UUID id strategy
+	UUID string `json:"uuid,omitempty"`
+	// RoleUser or RoleAdmin, Create defaults it to RoleUser
+	Role string `json:"role"`
+	// set by SoftDelete, soft deleted users are hidden from normal reads
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// false until MarkEmailVerified, changing the email resets it
+	EmailVerified bool `json:"email_verified"`
+	// last change through Update, Patch, a role or an email change
+	// nil if the user was never updated
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	// extra fields of the deployment, stored as one json object
+	// values read back are json types, so numbers are float64
+	Metadata map[string]any `json:"metadata,omitempty"`
+	// name shown to people, nil falls back to Username, see Name
+	DisplayName *string `json:"display_name,omitempty"`
+}
+
+// Name is the display name, or the username when there is none
+// use it wherever a user is shown to people
+func (u User) Name() string {
+	if u.DisplayName != nil && *u.DisplayName != "" {
+		return *u.DisplayName
+	}
+	return u.Username
+}
+
+// UserSummary is the id and username of a user, see ListNames
+type UserSummary struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+// PageResult is one page of users with the total count, see Page
+type PageResult struct {
+	Users []User `json:"users"`
+	Total int64  `json:"total"`
+	// HasNext is true when users follow after this page
+	HasNext bool `json:"has_next"`
+}
+
+// ValidationReport is what ValidateCSV found in a csv file
+type ValidationReport struct {
+	// Rows is how many rows were checked, the header not included
+	Rows int `json:"rows"`
+	// Problems are in file order, a row can have more than one
+	Problems []RowProblem `json:"problems"`
+}
+
+// RowProblem is one problem of a csv row
+type RowProblem struct {
+	Line int `json:"line"`
+	// Field is "username" or "email", empty when the problem is the whole row
+	Field string `json:"field,omitempty"`
+	Err   error  `json:"-"`
+	// Message is Err as text for json
+	Message string `json:"message"`
+}
+
+// Valid reports whether the file had no problems
+func (r ValidationReport) Valid() bool {
+	return len(r.Problems) == 0
+}
+
+func (r *ValidationReport) add(line int, field string, err error) {
+	r.Problems = append(r.Problems, RowProblem{Line: line, Field: field, Err: err, Message: err.Error()})
+}
+
+// roles a user can have
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+var validRoles = map[string]bool{
+	RoleUser:  true,
+	RoleAdmin: true,
+}
+
+// AuditEntry is one recorded change of a user
+// Snapshot holds the user row as json
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	Action    string          `json:"action"`
+	UserID    int64           `json:"user_id"`
+	Snapshot  json.RawMessage `json:"snapshot"`
+	CreatedAt time.Time       `json:"created_at"`
+	// user that made the change, nil when the system did
+	ActorID *int64 `json:"actor_id,omitempty"`
 }
@@ -0,0 +1,512 @@
+package userstore
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// config is the store settings after all options are applied
+type config struct {
+	// overwrite lets Backup replace an existing file
+	overwrite bool
+	// lowercased email domains checked by Create and Update
+	allowedDomains map[string]bool
+	deniedDomains  map[string]bool
+	// readOnly opens the file with mode=ro and rejects writes
+	readOnly bool
+	// defaultTimeout bounds operations whose context has no deadline
+	defaultTimeout time.Duration
+	// onCreate hooks run after a create is committed
+	onCreate []func(context.Context, *User)
+	// busy retry backoff, retries are off while retryBase is zero
+	retryBase time.Duration
+	retryMax  time.Duration
+	// jitter and sleep are replaced in tests
+	jitter *rand.Rand
+	sleep  func(context.Context, time.Duration) error
+	// clock is the source of "now" for expiries, nil means time.Now
+	clock func() time.Time
+	// idStrategy decides which public id new users get
+	idStrategy IDStrategy
+	// creates allowed per email domain in rateWindow, zero is unlimited
+	rateLimit  int
+	rateWindow time.Duration
+	// path is the database file, set by NewDb
+	path string
+	// fullText keeps an fts5 index of usernames and emails
+	fullText bool
+	// queryLogger sees every statement after it ran, nil disables logging
+	queryLogger func(sql string, args []any, dur time.Duration, err error)
+	// autoCheckpoint is PRAGMA wal_autocheckpoint in pages, zero keeps the sqlite default
+	autoCheckpoint int
+	// canonicalEmail stores and enforces canonical_email on writes
+	canonicalEmail bool
+	// NewDb tries opening openAttempts times while the file is busy
+	// waiting openBackoff * attempt in between
+	openAttempts int
+	openBackoff  time.Duration
+	// opener opens and pings the pool, replaced in tests
+	opener func(driver.Connector) (*sql.DB, error)
+	// busyTimeout is how long a connection waits for a lock
+	busyTimeout time.Duration
+	// emailKey turns on email encryption, see WithEmailEncryption
+	emailKey []byte
+	// tenant scopes every user query, see WithTenant
+	tenant int64
+	// journalMode is PRAGMA journal_mode, see WithJournalMode
+	journalMode string
+	// idOffset is the lowest id new users get, nil starts at 1
+	idOffset *int64
+	// dsnParams are appended to the file uri, see WithDSNParams
+	dsnParams map[string]string
+	// txOptions are used by every transaction the store begins, nil is the default
+	txOptions *sql.TxOptions
+	// strictPragmas fails NewDb when an optional pragma fails, see WithStrictPragmas
+	strictPragmas bool
+	// tombstones records hard deletes in the deletions table
+	tombstones bool
+	// checkSchema runs CheckSchema when the store opens
+	checkSchema bool
+	// emailOptional lets users be created without an email, see WithEmailRequired
+	emailOptional bool
+	// emailNotUnique lets users share an email, see WithEmailUnique
+	emailNotUnique bool
+	// replica serves the reads, see WithReadReplica
+	replica Store
+	// pragmaExec runs one pragma on a new connection, replaced in tests
+	pragmaExec func(conn *sqlite3.SQLiteConn, query string) error
+	// maxPageSize caps the limit of one page, set to the default by NewDb
+	maxPageSize int
+	// rejectLargePages returns ErrLimitTooLarge instead of clamping
+	rejectLargePages bool
+}
+
+// IDStrategy is how new users are identified to the outside
+type IDStrategy int
+
+const (
+	// IntegerID exposes only the sequential integer id
+	IntegerID IDStrategy = iota
+	// UUID also gives every new user a random uuid
+	// so ids handed out do not reveal how many users exist
+	UUID
+)
+
+// Option changes how the store is opened or behaves
+type Option func(*config)
+
+// WithOverwrite allows operations that write a database file
+// to replace a file that already exists
+func WithOverwrite(overwrite bool) Option {
+	return func(c *config) {
+		c.overwrite = overwrite
+	}
+}
+
+// WithAllowedEmailDomains only accepts emails from the given domains
+func WithAllowedEmailDomains(domains ...string) Option {
+	return func(c *config) {
+		c.allowedDomains = addDomains(c.allowedDomains, domains)
+	}
+}
+
+// WithDeniedEmailDomains rejects emails from the given domains
+func WithDeniedEmailDomains(domains ...string) Option {
+	return func(c *config) {
+		c.deniedDomains = addDomains(c.deniedDomains, domains)
+	}
+}
+
+func addDomains(set map[string]bool, domains []string) map[string]bool {
+	if set == nil {
+		set = make(map[string]bool)
+	}
+	for _, d := range domains {
+		set[strings.ToLower(strings.TrimPrefix(d, "@"))] = true
+	}
+	return set
+}
+
+// WithReadOnly opens the database read-only
+// writes return ErrReadOnly and migrations are skipped
+func WithReadOnly(readOnly bool) Option {
+	return func(c *config) {
+		c.readOnly = readOnly
+	}
+}
+
+// WithDefaultTimeout bounds every operation whose context has no deadline
+// contexts that already carry a deadline are left alone
+func WithDefaultTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.defaultTimeout = d
+	}
+}
+
+// WithOnCreate registers fn to run after a user is created and committed
+// so it never fires for a rolled back insert
+// fn runs synchronously, start a goroutine inside it for slow work
+func WithOnCreate(fn func(context.Context, *User)) Option {
+	return func(c *config) {
+		c.onCreate = append(c.onCreate, fn)
+	}
+}
+
+// WithRetryBackoff retries write transactions that fail with a busy error
+// waiting base * attempt plus a random jitter, never more than max
+func WithRetryBackoff(base, max time.Duration) Option {
+	return func(c *config) {
+		c.retryBase = base
+		c.retryMax = max
+	}
+}
+
+// WithJitterSource sets the random source for retry jitter
+// a seeded source makes the delays repeatable, the store locks it for concurrent writers
+func WithJitterSource(src rand.Source) Option {
+	return func(c *config) {
+		c.jitter = rand.New(&lockedSource{src: src})
+	}
+}
+
+// WithClock replaces time.Now for everything the store times
+// tests use it to move time forward deterministically
+func WithClock(clock func() time.Time) Option {
+	return func(c *config) {
+		c.clock = clock
+	}
+}
+
+// WithIDStrategy picks how new users are identified
+// the integer id stays the primary key either way
+func WithIDStrategy(strategy IDStrategy) Option {
+	return func(c *config) {
+		c.idStrategy = strategy
+	}
+}
+
+// WithCreateRateLimit allows at most perDomain creates
+// from one email domain within window
+// extra creates return ErrRateLimited
+func WithCreateRateLimit(perDomain int, window time.Duration) Option {
+	return func(c *config) {
+		c.rateLimit = perDomain
+		c.rateWindow = window
+	}
+}
+
+// StoreConfig describes how a store was opened
+// it is a copy, changing it does not affect the store
+type StoreConfig struct {
+	Path                  string
+	Table                 string
+	ReadOnly              bool
+	Overwrite             bool
+	DefaultTimeout        time.Duration
+	BusyTimeout           time.Duration
+	AllowedEmailDomains   []string
+	DeniedEmailDomains    []string
+	RetryBase             time.Duration
+	RetryMax              time.Duration
+	IDStrategy            IDStrategy
+	CreateRateLimit       int
+	CreateRateWindow      time.Duration
+	FullTextSearch        bool
+	AutoCheckpoint        int
+	EmailCanonicalization bool
+	EmailEncryption       bool
+	Tenant                int64
+	MaxPageSize           int
+	RejectLargePages      bool
+	JournalMode           string
+	IDOffset              int64
+	DSNParams             map[string]string
+	StrictPragmas         bool
+	DeletionTombstones    bool
+	CheckSchema           bool
+	EmailRequired         bool
+	EmailUnique           bool
+	ReadReplica           bool
+}
+
+// WithFullTextSearch maintains an fts5 index for FullTextSearch
+// sqlite must be built with fts5 (go build -tags sqlite_fts5)
+// or NewDb returns ErrFullTextUnavailable
+// once the index exists, every build writing to the file needs fts5
+func WithFullTextSearch(enabled bool) Option {
+	return func(c *config) {
+		c.fullText = enabled
+	}
+}
+
+// defaultBusyTimeout is PRAGMA busy_timeout unless WithBusyTimeout is set
+const defaultBusyTimeout = 5 * time.Second
+
+// defaultJournalMode is PRAGMA journal_mode unless WithJournalMode is set
+const defaultJournalMode = "WAL"
+
+// journalModes are the values sqlite accepts for PRAGMA journal_mode
+var journalModes = []string{"DELETE", "TRUNCATE", "PERSIST", "MEMORY", "WAL", "OFF"}
+
+// defaultMaxPageSize is the largest page unless WithMaxPageSize is set
+const defaultMaxPageSize = 1000
+
+// Config returns the effective configuration after options were applied
+func (s *sqlStore) Config() StoreConfig {
+	var idOffset int64
+	if s.cfg.idOffset != nil {
+		idOffset = *s.cfg.idOffset
+	}
+	return StoreConfig{
+		Path:                  s.cfg.path,
+		Table:                 "users",
+		ReadOnly:              s.cfg.readOnly,
+		Overwrite:             s.cfg.overwrite,
+		DefaultTimeout:        s.cfg.defaultTimeout,
+		BusyTimeout:           s.cfg.busyTimeout,
+		AllowedEmailDomains:   domainList(s.cfg.allowedDomains),
+		DeniedEmailDomains:    domainList(s.cfg.deniedDomains),
+		RetryBase:             s.cfg.retryBase,
+		RetryMax:              s.cfg.retryMax,
+		IDStrategy:            s.cfg.idStrategy,
+		CreateRateLimit:       s.cfg.rateLimit,
+		CreateRateWindow:      s.cfg.rateWindow,
+		FullTextSearch:        s.cfg.fullText,
+		AutoCheckpoint:        s.cfg.autoCheckpoint,
+		EmailCanonicalization: s.cfg.canonicalEmail,
+		EmailEncryption:       s.crypt != nil,
+		Tenant:                s.cfg.tenant,
+		MaxPageSize:           s.cfg.maxPageSize,
+		RejectLargePages:      s.cfg.rejectLargePages,
+		JournalMode:           s.cfg.journalMode,
+		IDOffset:              idOffset,
+		DSNParams:             maps.Clone(s.cfg.dsnParams),
+		StrictPragmas:         s.cfg.strictPragmas,
+		DeletionTombstones:    s.cfg.tombstones,
+		CheckSchema:           s.cfg.checkSchema,
+		EmailRequired:         !s.cfg.emailOptional,
+		EmailUnique:           !s.cfg.emailNotUnique,
+		ReadReplica:           s.cfg.replica != nil,
+	}
+}
+
+func domainList(set map[string]bool) []string {
+	var domains []string
+	for d := range set {
+		domains = append(domains, d)
+	}
+	slices.Sort(domains)
+	return domains
+}
+
+// WithQueryLogger calls fn after every statement the store runs
+// with the sql, its arguments, how long it took and its error
+// arguments that look like emails are masked before fn sees them
+// fn runs synchronously on the query path so keep it cheap
+func WithQueryLogger(fn func(sql string, args []any, dur time.Duration, err error)) Option {
+	return func(c *config) {
+		c.queryLogger = fn
+	}
+}
+
+// WithAutoCheckpoint sets PRAGMA wal_autocheckpoint to n pages
+// sqlite checkpoints the WAL once it grows past n pages
+// zero keeps the sqlite default of 1000, a negative n turns it off
+// leaving Checkpoint as the only way to shrink the WAL
+func WithAutoCheckpoint(n int) Option {
+	return func(c *config) {
+		c.autoCheckpoint = n
+	}
+}
+
+// WithEmailCanonicalization stores the canonical form of every written email
+// in canonical_email, which is unique, so aliases like a.b+x@gmail.com
+// and ab@gmail.com count as the same address and return ErrDuplicateUser
+// the original email is kept as is
+// rows written while it is off have no canonical form and never collide
+func WithEmailCanonicalization(enabled bool) Option {
+	return func(c *config) {
+		c.canonicalEmail = enabled
+	}
+}
+
+// WithOpenRetry makes NewDb try up to attempts times
+// while the database is locked or busy, waiting backoff * attempt in between
+// other errors, like a bad path, fail right away
+func WithOpenRetry(attempts int, backoff time.Duration) Option {
+	return func(c *config) {
+		c.openAttempts = attempts
+		c.openBackoff = backoff
+	}
+}
+
+// WithBusyTimeout sets how long a connection waits for another one
+// to release its lock before failing with a busy error
+// zero or less keeps the default of 5 seconds
+func WithBusyTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.busyTimeout = d
+	}
+}
+
+// WithEmailEncryption stores emails AES-GCM encrypted with a key derived from key
+// and a keyed hash of the lowercased email in email_hash, which is unique
+// lookups and duplicate checks go through the hash, so emails that differ
+// only in case count as the same address
+// key must be 16, 24 or 32 bytes or NewDb returns ErrInvalidKey
+// the same key is needed to read the data back, losing it loses the emails
+// CountByDomain decrypts every row, FullTextSearch can not match emails
+// and audit snapshots keep the email encrypted
+func WithEmailEncryption(key []byte) Option {
+	return func(c *config) {
+		c.emailKey = key
+	}
+}
+
+// WithTenant scopes the store to one tenant
+// users are created in it and reads and writes only see its users
+// usernames and emails are unique per tenant, so the same username
+// can exist in two tenants, stores without it use tenant 0
+// Truncate, Renumber, FindOrphans and Backup still cover the whole file
+func WithTenant(id int64) Option {
+	return func(c *config) {
+		c.tenant = id
+	}
+}
+
+// WithMaxPageSize caps how many users List, Query, ListRecentlyUpdated
+// and ChangesSince return at once, so a caller can not ask for a page
+// large enough to exhaust memory
+// bigger limits are clamped unless WithRejectLargePages is set
+// zero or less keeps the default of 1000
+func WithMaxPageSize(n int) Option {
+	return func(c *config) {
+		c.maxPageSize = n
+	}
+}
+
+// WithRejectLargePages makes a limit above the max page size
+// fail with ErrLimitTooLarge instead of being clamped
+func WithRejectLargePages(enabled bool) Option {
+	return func(c *config) {
+		c.rejectLargePages = enabled
+	}
+}
+
+// WithJournalMode sets PRAGMA journal_mode, WAL by default
+// WAL lets readers and a writer work at the same time but needs shared memory
+// between processes, which network filesystems like NFS do not provide
+// and can corrupt the file, use DELETE or TRUNCATE there
+// the rollback modes block readers while a write commits
+// and run with synchronous = FULL since NORMAL is only safe with WAL
+// the mode is case insensitive, an unknown one makes NewDb return ErrInvalidJournalMode
+// read-only stores keep whatever mode the file has
+func WithJournalMode(mode string) Option {
+	return func(c *config) {
+		c.journalMode = strings.ToUpper(mode)
+	}
+}
+
+// WithoutWAL is WithJournalMode("DELETE")
+func WithoutWAL() Option {
+	return WithJournalMode("DELETE")
+}
+
+// WithIDOffset makes the first user of a new file get id start
+// so stores of different shards can be merged without id collisions
+// it only raises the sequence, a file whose ids are already past start keeps counting
+// Truncate starts over at start, Renumber still numbers from 1
+// start must be positive or NewDb returns ErrInvalidIDOffset
+func WithIDOffset(start int64) Option {
+	return func(c *config) {
+		c.idOffset = &start
+	}
+}
+
+// WithDSNParams adds query parameters to the sqlite file uri the store opens
+// useful ones are cache=shared to share one cache between connections,
+// immutable=1 for files on read-only media that nothing else changes,
+// nolock=1 to skip file locking and vfs to pick an os interface
+// keys are letters, digits and underscores and values are escaped,
+// so neither can change the path, anything else makes NewDb return ErrInvalidDSNParam
+// mode is set by WithReadOnly and can not be passed here
+func WithDSNParams(params map[string]string) Option {
+	return func(c *config) {
+		c.dsnParams = maps.Clone(params)
+	}
+}
+
+// WithTxOptions sets the options of the transactions the store's
+// own methods begin, like Create or Update
+// sqlite transactions are always serializable so the isolation level
+// has no effect, ReadOnly is enforced and makes every write fail,
+// which suits a store that only runs read batches
+// BeginStoreTx and WithTx take their own options
+func WithTxOptions(opts *sql.TxOptions) Option {
+	return func(c *config) {
+		c.txOptions = opts
+	}
+}
+
+// WithStrictPragmas makes NewDb fail when foreign_keys or journal_mode
+// can not be set, by default a warning is logged with slog and
+// the store runs without them, other pragmas always fail NewDb
+func WithStrictPragmas(strict bool) Option {
+	return func(c *config) {
+		c.strictPragmas = strict
+	}
+}
+
+// WithDeletionTombstones makes Delete and PurgeDeleted write the id of
+// every removed user and when it happened to the deletions table,
+// in the same transaction, so a deletion can be counted without personal data
+// the audit log still keeps its snapshots
+func WithDeletionTombstones(enabled bool) Option {
+	return func(c *config) {
+		c.tombstones = enabled
+	}
+}
+
+// WithSchemaCheck makes NewDb run CheckSchema after migrating
+// so a binary deployed against a database it does not match fails at startup
+// instead of with scan errors later
+func WithSchemaCheck(enabled bool) Option {
+	return func(c *config) {
+		c.checkSchema = enabled
+	}
+}
+
+// WithEmailRequired(false) lets users be created and updated without an email
+// for accounts like service accounts, an empty email is stored as an empty string
+// and never collides with another, emails are required by default
+func WithEmailRequired(required bool) Option {
+	return func(c *config) {
+		c.emailOptional = !required
+	}
+}
+
+// WithEmailUnique(false) lets users share an email
+// NewDb turns the unique email indexes into plain ones
+// and opening again with the default turns them back
+// which fails with ErrDuplicateUser while users share an email
+func WithEmailUnique(unique bool) Option {
+	return func(c *config) {
+		c.emailNotUnique = !unique
+	}
+}
+
+// WithReadReplica makes NewDb return a ReplicaStore that reads from replica
+// and writes to the opened database, see NewReplicaStore
+func WithReadReplica(replica Store) Option {
+	return func(c *config) {
+		c.replica = replica
+	}
+}
@@ -0,0 +1,80 @@
+package userstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// OrphanReport lists rows that reference users which do not exist
+type OrphanReport struct {
+	// AuditEntries are audit_log ids of users that are gone without a recorded delete
+	// entries of deleted users are history and not reported
+	AuditEntries []int64
+	// CreatedBy are ids of users whose created_by points at no user
+	CreatedBy []int64
+	// EmailChanges are ids of users with pending email changes that do not exist
+	EmailChanges []int64
+	// UsernameHistory are ids of users with old usernames that do not exist
+	UsernameHistory []int64
+}
+
+// Empty reports whether no orphans were found
+func (r OrphanReport) Empty() bool {
+	return len(r.AuditEntries) == 0 && len(r.CreatedBy) == 0 && len(r.EmailChanges) == 0 && len(r.UsernameHistory) == 0
+}
+
+// FindOrphans looks for dependent rows pointing at missing users
+// it only reads, fixing what it finds is up to the caller
+// with foreign keys on only the audit log can collect orphans
+// so the other lists stay empty unless keys were off while writing
+func (s *sqlStore) FindOrphans(ctx context.Context) (OrphanReport, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	var report OrphanReport
+	checks := []struct {
+		query string
+		dest  *[]int64
+	}{
+		{`SELECT a.id FROM audit_log a
+		WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.id = a.user_id)
+		AND NOT EXISTS (SELECT 1 FROM audit_log d WHERE d.user_id = a.user_id AND d.action = '` + AuditActionDelete + `')
+		ORDER BY a.id`, &report.AuditEntries},
+		{`SELECT c.id FROM users c
+		WHERE c.created_by IS NOT NULL AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = c.created_by)
+		ORDER BY c.id`, &report.CreatedBy},
+		{`SELECT DISTINCT e.user_id FROM email_changes e
+		WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.id = e.user_id)
+		ORDER BY e.user_id`, &report.EmailChanges},
+		{`SELECT DISTINCT h.user_id FROM username_history h
+		WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.id = h.user_id)
+		ORDER BY h.user_id`, &report.UsernameHistory},
+	}
+	for _, c := range checks {
+		ids, err := queryIDs(ctx, s.db, c.query)
+		if err != nil {
+			return OrphanReport{}, fmt.Errorf("failed to find orphans : %w", err)
+		}
+		*c.dest = ids
+	}
+	return report, nil
+}
+
+// queryIDs collects the single integer column of query
+func queryIDs(ctx context.Context, db *sql.DB, query string) ([]int64, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,60 @@
+package userstore
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// Find orphans test
+func TestFindOrphans(t *testing.T) {
+	// a file so the extra connection below sees the same database
+	store := storeAt(t, filepath.Join(t.TempDir(), "users.db"))
+	ctx := context.Background()
+	parent := &User{Username: "parent", Email: "parent@test.com"}
+	_ = store.Create(ctx, parent)
+	gone := &User{Username: "gone", Email: "gone@test.com"}
+	_ = store.Create(ctx, gone)
+	_ = store.Delete(ctx, gone.ID)
+
+	report, err := store.FindOrphans(ctx)
+	if err != nil {
+		t.Fatalf("FindOrphans failed : %v", err)
+	}
+	if !report.Empty() {
+		t.Fatalf("Expected no orphans, deleted users are history, got %+v", report)
+	}
+
+	// foreign_keys is per connection so the orphan is written on one
+	conn, err := store.(*sqlStore).db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	queries := []string{
+		`PRAGMA foreign_keys = OFF`,
+		`INSERT INTO users (username, email, created_by) VALUES ('orphan', 'orphan@test.com', 999)`,
+		`INSERT INTO audit_log (action, user_id, snapshot) VALUES ('update', 998, '{}')`,
+		`INSERT INTO username_history (user_id, username, changed_at) VALUES (997, 'old', CURRENT_TIMESTAMP)`,
+		`PRAGMA foreign_keys = ON`,
+	}
+	for _, q := range queries {
+		if _, err := conn.ExecContext(ctx, q); err != nil {
+			t.Fatalf("%s : %v", q, err)
+		}
+	}
+
+	report, err = store.FindOrphans(ctx)
+	if err != nil {
+		t.Fatalf("FindOrphans failed : %v", err)
+	}
+	if len(report.CreatedBy) != 1 || report.CreatedBy[0] != gone.ID+1 {
+		t.Errorf("Expected the orphan user to be reported, got %v", report.CreatedBy)
+	}
+	if len(report.AuditEntries) != 1 {
+		t.Errorf("Expected 1 orphan audit entry, got %v", report.AuditEntries)
+	}
+	if len(report.UsernameHistory) != 1 || report.UsernameHistory[0] != 997 {
+		t.Errorf("Expected the orphan username history to be reported, got %v", report.UsernameHistory)
+	}
+}
@@ -0,0 +1,110 @@
+package userstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// patchFields are the columns Patch may change, all of them hold strings
+// an empty display_name clears it
+var patchFields = map[string]bool{
+	"username":     true,
+	"email":        true,
+	"role":         true,
+	"display_name": true,
+}
+
+// Patch changes only the columns named in fields and leaves the rest as stored
+// the patched user is validated like Update does before anything is written
+// unknown names or non string values return ErrInvalidField
+func (s *sqlStore) Patch(ctx context.Context, id int64, fields map[string]any) error {
+	if s.cfg.readOnly {
+		return ErrReadOnly
+	}
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	names := make([]string, 0, len(fields))
+	for name, value := range fields {
+		if _, ok := value.(string); !ok || !patchFields[name] {
+			return fmt.Errorf("field %q : %w", name, ErrInvalidField)
+		}
+		names = append(names, name)
+	}
+	// a stable column order keeps the sql the same for the same fields
+	slices.Sort(names)
+
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		current, err := s.readUserTx(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if current.DeletedAt != nil {
+			return ErrUserNotFound
+		}
+
+		patched := *current
+		set := make([]string, 0, len(names)+1)
+		args := make([]any, 0, len(names)+1)
+		for _, name := range names {
+			value := fields[name].(string)
+			switch name {
+			case "username":
+				patched.Username = value
+			case "email":
+				patched.Email = value
+			case "role":
+				patched.Role = value
+			case "display_name":
+				patched.DisplayName = &value
+				set = append(set, name+" = ?")
+				args = append(args, displayName(&value))
+				continue
+			}
+			set = append(set, name+" = ?")
+			args = append(args, value)
+		}
+		if i := slices.Index(names, "email"); i >= 0 {
+			stored, err := s.storedEmail(patched.Email)
+			if err != nil {
+				return err
+			}
+			args[i] = stored
+		}
+		if patched.Role == "" {
+			return ErrInvalidRole
+		}
+		if err := s.validate(&patched); err != nil {
+			return err
+		}
+		if len(set) == 0 {
+			return nil
+		}
+		// a new email has not been verified yet, a new case of the same one has
+		if patched.Email != current.Email {
+			set = append(set, "canonical_email = ?", "email_hash = ?", "email_lower = ?")
+			args = append(args, s.canonical(patched.Email), s.emailHash(patched.Email), s.emailLower(patched.Email))
+			if lowerEmail(patched.Email) != lowerEmail(current.Email) {
+				set = append(set, "email_verified = 0")
+			}
+		}
+		set = append(set, "updated_at = ?")
+		args = append(args, s.now().UTC())
+
+		query := `UPDATE users SET ` + strings.Join(set, ", ") + ` WHERE id = ?`
+		if _, err := tx.ExecContext(ctx, query, append(args, id)...); err != nil {
+			if isUniqueViolation(err) {
+				return ErrDuplicateUser
+			}
+			return fmt.Errorf("failed to patch user : %w", err)
+		}
+
+		stored, err := s.readUserTx(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		return s.writeAudit(ctx, tx, AuditActionUpdate, stored)
+	})
+}
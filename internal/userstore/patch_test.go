@@ -0,0 +1,55 @@
+package userstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// Patch only the email test
+func TestPatchEmail(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+	u := &User{Username: "keep", Email: "old@test.com"}
+	_ = store.Create(ctx, u)
+
+	if err := store.Patch(ctx, u.ID, map[string]any{"email": "new@test.com"}); err != nil {
+		t.Fatalf("Patch failed : %v", err)
+	}
+	got, _ := store.GetById(ctx, u.ID)
+	if got.Email != "new@test.com" {
+		t.Errorf("Expected patched email, got %q", got.Email)
+	}
+	if got.Username != "keep" || got.Role != RoleUser {
+		t.Errorf("Expected other fields untouched, got %+v", got)
+	}
+	entries, _ := store.ListAudit(ctx, u.ID)
+	if len(entries) != 2 || entries[1].Action != AuditActionUpdate {
+		t.Errorf("Expected an update audit entry, got %+v", entries)
+	}
+}
+
+// Patch errors test
+func TestPatchErrors(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+	u := &User{Username: "p", Email: "p@test.com"}
+	_ = store.Create(ctx, u)
+	_ = store.Create(ctx, &User{Username: "other", Email: "other@test.com"})
+
+	if err := store.Patch(ctx, 999, map[string]any{"email": "x@test.com"}); err != ErrUserNotFound {
+		t.Errorf("Expected user not found, got %v", err)
+	}
+	if err := store.Patch(ctx, u.ID, map[string]any{"password": "x"}); !errors.Is(err, ErrInvalidField) {
+		t.Errorf("Expected invalid field for unknown name, got %v", err)
+	}
+	if err := store.Patch(ctx, u.ID, map[string]any{"username": 42}); !errors.Is(err, ErrInvalidField) {
+		t.Errorf("Expected invalid field for wrong type, got %v", err)
+	}
+	if err := store.Patch(ctx, u.ID, map[string]any{"role": "boss"}); err != ErrInvalidRole {
+		t.Errorf("Expected invalid role, got %v", err)
+	}
+	if err := store.Patch(ctx, u.ID, map[string]any{"username": "other"}); err != ErrDuplicateUser {
+		t.Errorf("Expected duplicate user, got %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package userstore
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// loggingConn reports every Exec and Query of a connection to log
+// everything else goes straight to the embedded sqlite connection
+type loggingConn struct {
+	*sqlite3.SQLiteConn
+	log func(sql string, args []any, dur time.Duration, err error)
+}
+
+func (c *loggingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	result, err := c.SQLiteConn.ExecContext(ctx, query, args)
+	c.log(query, maskArgs(args), time.Since(start), err)
+	return result, err
+}
+
+// QueryContext times the query up to the first row, reading the rows is not included
+func (c *loggingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := c.SQLiteConn.QueryContext(ctx, query, args)
+	c.log(query, maskArgs(args), time.Since(start), err)
+	return rows, err
+}
+
+// maskArgs copies the bound values with emails masked
+func maskArgs(args []driver.NamedValue) []any {
+	masked := make([]any, len(args))
+	for i, a := range args {
+		if str, ok := a.Value.(string); ok {
+			masked[i] = maskEmail(str)
+			continue
+		}
+		masked[i] = a.Value
+	}
+	return masked
+}
+
+// maskEmail keeps the first letter and the domain of anything shaped like an email
+// a***@x.com, other strings are returned as they are
+func maskEmail(s string) string {
+	local, domain, ok := strings.Cut(s, "@")
+	if !ok || local == "" || domain == "" || strings.ContainsAny(s, " \t\n") {
+		return s
+	}
+	return local[:1] + "***@" + domain
+}
@@ -0,0 +1,51 @@
+package userstore
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Query logger test
+func TestQueryLogger(t *testing.T) {
+	type logged struct {
+		sql  string
+		args []any
+	}
+	var entries []logged
+	logger := func(sql string, args []any, dur time.Duration, err error) {
+		entries = append(entries, logged{sql, args})
+	}
+	store := storeWithOptions(t, WithQueryLogger(logger))
+
+	err := store.Create(context.Background(), &User{Username: "log", Email: "alice@x.com"})
+	if err != nil {
+		t.Fatalf("Create failed : %v", err)
+	}
+
+	for _, e := range entries {
+		if !strings.HasPrefix(e.sql, "INSERT INTO users") {
+			continue
+		}
+		if e.args[1] != "a***@x.com" {
+			t.Errorf("Expected masked email, got %v", e.args[1])
+		}
+		return
+	}
+	t.Fatal("Expected the insert to be logged")
+}
+
+// Email masking test
+func TestMaskEmail(t *testing.T) {
+	cases := map[string]string{
+		"alice@x.com": "a***@x.com",
+		"plain":       "plain",
+		"@x.com":      "@x.com",
+	}
+	for in, want := range cases {
+		if got := maskEmail(in); got != want {
+			t.Errorf("maskEmail(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package userstore
+
+import (
+	"sync"
+	"time"
+)
+
+// domainLimiter counts creates per email domain in a sliding window
+// it lives in memory so the count starts over when the process restarts
+type domainLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	hits   map[string][]time.Time
+	// swept is when domains with no hit left in the window were last dropped
+	swept time.Time
+}
+
+func newDomainLimiter(limit int, window time.Duration) *domainLimiter {
+	return &domainLimiter{
+		limit:  limit,
+		window: window,
+		hits:   make(map[string][]time.Time),
+	}
+}
+
+// reserve takes a slot for domain at now
+// it returns false when the domain already used every slot in the window
+func (l *domainLimiter) reserve(domain string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// drop hits that left the window
+	hits := l.hits[domain]
+	cutoff := now.Add(-l.window)
+	i := 0
+	for i < len(hits) && !hits[i].After(cutoff) {
+		i++
+	}
+	hits = hits[i:]
+
+	// once per window forget domains that stopped creating
+	// so the map does not keep every domain ever seen
+	if now.Sub(l.swept) >= l.window {
+		for d, h := range l.hits {
+			if len(h) == 0 || !h[len(h)-1].After(cutoff) {
+				delete(l.hits, d)
+			}
+		}
+		l.swept = now
+	}
+
+	if len(hits) >= l.limit {
+		l.hits[domain] = hits
+		return false
+	}
+	l.hits[domain] = append(hits, now)
+	return true
+}
+
+// release gives back a slot taken at "at" when the create failed
+func (l *domainLimiter) release(domain string, at time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	hits := l.hits[domain]
+	for i := len(hits) - 1; i >= 0; i-- {
+		if hits[i].Equal(at) {
+			hits = append(hits[:i], hits[i+1:]...)
+			if len(hits) == 0 {
+				delete(l.hits, domain)
+			} else {
+				l.hits[domain] = hits
+			}
+			return
+		}
+	}
+}
+
+// reserveCreate takes a rate limit slot for the email domain of user
+// release gives it back when the create fails, it does nothing without a limiter
+func (s *sqlStore) reserveCreate(user *User) (release func(), err error) {
+	if s.limiter == nil {
+		return func() {}, nil
+	}
+	domain, at := emailDomain(user.Email), s.now()
+	if !s.limiter.reserve(domain, at) {
+		return nil, ErrRateLimited
+	}
+	return func() { s.limiter.release(domain, at) }, nil
+}
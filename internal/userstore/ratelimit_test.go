@@ -0,0 +1,101 @@
+package userstore
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Create rate limit per domain test
+func TestCreateRateLimit(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	store := storeWithOptions(t, WithClock(clock.Now), WithCreateRateLimit(2, time.Hour))
+	ctx := context.Background()
+
+	_ = store.Create(ctx, &User{Username: "a1", Email: "a1@spam.io"})
+	clock.now = clock.now.Add(time.Minute)
+	_ = store.Create(ctx, &User{Username: "a2", Email: "a2@spam.io"})
+
+	err := store.Create(ctx, &User{Username: "a3", Email: "a3@SPAM.io"})
+	if err != ErrRateLimited {
+		t.Fatalf("Expected rate limited error, got %v", err)
+	}
+
+	// other domains have their own budget
+	if err := store.Create(ctx, &User{Username: "b1", Email: "b1@ok.io"}); err != nil {
+		t.Fatalf("Expected other domain to pass, got %v", err)
+	}
+
+	// once the first create leaves the window a slot frees up
+	clock.now = clock.now.Add(time.Hour)
+	if err := store.Create(ctx, &User{Username: "a3", Email: "a3@spam.io"}); err != nil {
+		t.Fatalf("Expected create after window to pass, got %v", err)
+	}
+}
+
+// Failed creates do not count test
+func TestRateLimitIgnoresFailures(t *testing.T) {
+	store := storeWithOptions(t, WithCreateRateLimit(1, time.Hour))
+	ctx := context.Background()
+
+	_ = store.Create(ctx, &User{Username: "x", Email: "x@a.io"})
+	// duplicate username, the create fails
+	_ = store.Create(ctx, &User{Username: "x", Email: "y@b.io"})
+	if err := store.Create(ctx, &User{Username: "z", Email: "z@b.io"}); err != nil {
+		t.Fatalf("Expected failed create to not use the slot, got %v", err)
+	}
+}
+
+// Batch and csv import rate limit test
+func TestRateLimitBatchAndImport(t *testing.T) {
+	store := storeWithOptions(t, WithCreateRateLimit(2, time.Hour))
+	ctx := context.Background()
+
+	results, err := store.BatchCreatePartial(ctx, []*User{
+		{Username: "a1", Email: "a1@spam.io"},
+		{Username: "a1", Email: "dup@spam.io"},
+		{Username: "a2", Email: "a2@spam.io"},
+		{Username: "a3", Email: "a3@spam.io"},
+	})
+	if err != nil {
+		t.Fatalf("BatchCreatePartial failed : %v", err)
+	}
+	// the duplicate gives its slot back so a2 still fits
+	if results[1].Err != ErrDuplicateUser || results[2].Err != nil || results[3].Err != ErrRateLimited {
+		t.Errorf("Unexpected results %+v", results)
+	}
+
+	_, _, err = store.ImportCSV(ctx, strings.NewReader("username,email\nb1,b1@spam.io\n"), false)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ImportCSV to be rate limited, got %v", err)
+	}
+
+	// a failed import gives back the slots of the rows it did insert
+	_, _, err = store.ImportCSV(ctx, strings.NewReader("username,email\nc1,c1@ok.io\nc2,c2@ok.io\nc3,c3@ok.io\n"), false)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("Expected the third row to be rate limited, got %v", err)
+	}
+	if err := store.Create(ctx, &User{Username: "c4", Email: "c4@ok.io"}); err != nil {
+		t.Errorf("Expected the slots of the rolled back import to be free, got %v", err)
+	}
+}
+
+// Idle domains are forgotten test
+func TestRateLimitForgetsDomains(t *testing.T) {
+	l := newDomainLimiter(1, time.Hour)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	l.reserve("a.io", now)
+	l.reserve("b.io", now)
+	l.release("b.io", now)
+	if _, ok := l.hits["b.io"]; ok {
+		t.Error("Expected a released domain with no hits to be dropped")
+	}
+
+	l.reserve("c.io", now.Add(2*time.Hour))
+	if _, ok := l.hits["a.io"]; ok || len(l.hits) != 1 {
+		t.Errorf("Expected only c.io to be kept, got %v", l.hits)
+	}
+}
@@ -0,0 +1,72 @@
+package userstore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Read-only store test
+func TestReadOnlyStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "users.db")
+
+	rw, err := NewDb(path)
+	if err != nil {
+		t.Fatalf("Create DB: %v", err)
+	}
+	u := &User{Username: "ro", Email: "ro@test.com"}
+	_ = rw.Create(ctx, u)
+	_ = rw.Close()
+
+	store := storeAt(t, path, WithReadOnly(true))
+
+	if _, err := store.GetById(ctx, u.ID); err != nil {
+		t.Errorf("GetById failed : %v", err)
+	}
+	if users, err := store.ListAll(ctx); err != nil || len(users) != 1 {
+		t.Errorf("ListAll failed : %v %v", users, err)
+	}
+	if n, err := store.Count(ctx); err != nil || n != 1 {
+		t.Errorf("Count failed : %d %v", n, err)
+	}
+
+	if err := store.Create(ctx, &User{Username: "x", Email: "x@test.com"}); err != ErrReadOnly {
+		t.Errorf("Expected read-only error on create, got %v", err)
+	}
+	if err := store.Update(ctx, u); err != ErrReadOnly {
+		t.Errorf("Expected read-only error on update, got %v", err)
+	}
+	if err := store.Delete(ctx, u.ID); err != ErrReadOnly {
+		t.Errorf("Expected read-only error on delete, got %v", err)
+	}
+}
+
+// Read-only store without schema test
+func TestReadOnlyMissingSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.db")
+	// create the file without the users table
+	rw, err := NewDb(path)
+	if err != nil {
+		t.Fatalf("Create DB: %v", err)
+	}
+	_, _ = rw.(*sqlStore).db.Exec(`DROP TABLE users`)
+	_ = rw.Close()
+
+	if _, err := NewDb(path, WithReadOnly(true)); err != ErrSchemaMissing {
+		t.Fatalf("Expected schema missing error, got %v", err)
+	}
+}
+
+// Read-only store on an empty file test
+func TestReadOnlyEmptyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.db")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatalf("Create file : %v", err)
+	}
+
+	if _, err := NewDb(path, WithReadOnly(true)); err != ErrSchemaMissing {
+		t.Fatalf("Expected schema missing error, got %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package userstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// RenameUser changes the username of id and records the old one
+// in username_history in the same transaction
+// the new name is trimmed and checked like Update does,
+// a name another user has returns ErrDuplicateUser
+// renaming to the current name does nothing
+func (s *sqlStore) RenameUser(ctx context.Context, id int64, newUsername string) error {
+	if s.cfg.readOnly {
+		return ErrReadOnly
+	}
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	newUsername = strings.TrimSpace(newUsername)
+	if newUsername == "" {
+		return fmt.Errorf("username is required : %w", ErrInvalidField)
+	}
+
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		current, err := s.readUserTx(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if current.DeletedAt != nil {
+			return ErrUserNotFound
+		}
+		if current.Username == newUsername {
+			return nil
+		}
+		renamed := *current
+		renamed.Username = newUsername
+		if err := s.validate(&renamed); err != nil {
+			return err
+		}
+
+		now := s.now().UTC()
+		query := `UPDATE users SET username = ?, updated_at = ? WHERE id = ?`
+		if _, err := tx.ExecContext(ctx, query, newUsername, now, id); err != nil {
+			if isUniqueViolation(err) {
+				return ErrDuplicateUser
+			}
+			return fmt.Errorf("failed to rename user : %w", err)
+		}
+		query = `INSERT INTO username_history (user_id, username, changed_at) VALUES (?, ?, ?)`
+		if _, err := tx.ExecContext(ctx, query, id, current.Username, now); err != nil {
+			return fmt.Errorf("failed to record username history : %w", err)
+		}
+
+		stored, err := s.readUserTx(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		return s.writeAudit(ctx, tx, AuditActionUpdate, stored)
+	})
+}
+
+// UsernameHistory returns the usernames id had before, oldest first
+// renames through Update or Patch are not recorded
+func (s *sqlStore) UsernameHistory(ctx context.Context, id int64) ([]string, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	query := `SELECT h.username FROM username_history h JOIN users u ON u.id = h.user_id
+	WHERE h.user_id = ? AND u.tenant_id = ? ORDER BY h.id`
+	rows, err := s.conn().QueryContext(ctx, query, id, s.cfg.tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list username history : %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan username : %w", err)
+		}
+		names = append(names, name)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration : %w", err)
+	}
+	return names, nil
+}
@@ -0,0 +1,55 @@
+package userstore
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+// Rename with history test
+func TestRenameUser(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	u := &User{Username: "first", Email: "rename@test.com"}
+	_ = store.Create(ctx, u)
+
+	if err := store.RenameUser(ctx, u.ID, " second "); err != nil {
+		t.Fatalf("RenameUser failed : %v", err)
+	}
+	if err := store.RenameUser(ctx, u.ID, "third"); err != nil {
+		t.Fatalf("RenameUser failed : %v", err)
+	}
+
+	got, _ := store.GetById(ctx, u.ID)
+	if got.Username != "third" || got.UpdatedAt == nil {
+		t.Errorf("Expected renamed user, got %+v", got)
+	}
+	history, err := store.UsernameHistory(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("UsernameHistory failed : %v", err)
+	}
+	if !slices.Equal(history, []string{"first", "second"}) {
+		t.Errorf("Expected [first second], got %v", history)
+	}
+}
+
+// Conflicting rename test
+func TestRenameUserTaken(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	u := &User{Username: "mine", Email: "mine@test.com"}
+	_ = store.Create(ctx, u)
+	_ = store.Create(ctx, &User{Username: "taken", Email: "taken@test.com"})
+
+	if err := store.RenameUser(ctx, u.ID, "taken"); err != ErrDuplicateUser {
+		t.Fatalf("Expected duplicate user, got %v", err)
+	}
+	if history, _ := store.UsernameHistory(ctx, u.ID); len(history) != 0 {
+		t.Errorf("Expected no history after a failed rename, got %v", history)
+	}
+	if err := store.RenameUser(ctx, 999, "nobody"); err != ErrUserNotFound {
+		t.Errorf("Expected user not found, got %v", err)
+	}
+}
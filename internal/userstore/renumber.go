@@ -0,0 +1,51 @@
+package userstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Renumber rewrites every user id to 1..N ordered by created_at
+// and resets the id sequence so the next user gets N+1
+//
+// WARNING this is an admin tool for demos and cleanups, not for live data
+// every id handed out before is invalid afterwards, callers holding ids,
+// uuids aside, will point at the wrong user or at nothing
+// created_by, email_changes, username_history and audit_log user and actor ids of existing users are moved
+// to the new ids, but audit entries of deleted users keep their old id
+// which may now belong to someone else, and audit snapshots are not rewritten
+func (s *sqlStore) Renumber(ctx context.Context) error {
+	if s.cfg.readOnly {
+		return ErrReadOnly
+	}
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		queries := []string{
+			// parent ids change before their children, check keys at commit
+			`PRAGMA defer_foreign_keys = ON`,
+			`CREATE TEMP TABLE renumber AS
+			SELECT id AS old_id, ROW_NUMBER() OVER (ORDER BY created_at, id) AS new_id FROM users`,
+			// negate first so new ids never collide with ids not moved yet
+			`UPDATE users SET id = -id`,
+			`UPDATE users SET id = (SELECT new_id FROM renumber WHERE old_id = -users.id)`,
+			`UPDATE users SET created_by = (SELECT new_id FROM renumber WHERE old_id = users.created_by)
+			WHERE created_by IS NOT NULL`,
+			`UPDATE email_changes SET user_id = (SELECT new_id FROM renumber WHERE old_id = email_changes.user_id)`,
+			`UPDATE username_history SET user_id = (SELECT new_id FROM renumber WHERE old_id = username_history.user_id)`,
+			`UPDATE audit_log SET user_id = (SELECT new_id FROM renumber WHERE old_id = audit_log.user_id)
+			WHERE user_id IN (SELECT old_id FROM renumber)`,
+			`UPDATE audit_log SET actor_id = (SELECT new_id FROM renumber WHERE old_id = audit_log.actor_id)
+			WHERE actor_id IN (SELECT old_id FROM renumber)`,
+			`UPDATE sqlite_sequence SET seq = (SELECT COUNT(*) FROM users) WHERE name = 'users'`,
+			`DROP TABLE temp.renumber`,
+		}
+		for _, q := range queries {
+			if _, err := tx.ExecContext(ctx, q); err != nil {
+				return fmt.Errorf("failed to renumber users : %w", err)
+			}
+		}
+		return nil
+	})
+}
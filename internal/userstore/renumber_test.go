@@ -0,0 +1,46 @@
+package userstore
+
+import (
+	"context"
+	"testing"
+)
+
+// Renumber after delete test
+func TestRenumber(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+	first := &User{Username: "first", Email: "first@test.com"}
+	middle := &User{Username: "middle", Email: "middle@test.com"}
+	_ = store.Create(ctx, first)
+	_ = store.Create(ctx, middle)
+	last := &User{Username: "last", Email: "last@test.com", CreatedBy: &first.ID}
+	_ = store.Create(ctx, last)
+	_ = store.Delete(ctx, middle.ID)
+
+	if err := store.Renumber(ctx); err != nil {
+		t.Fatalf("Renumber failed : %v", err)
+	}
+
+	users, _ := store.ListAll(ctx)
+	if len(users) != 2 {
+		t.Fatalf("Expected 2 users, got %d", len(users))
+	}
+	if users[0].ID != 1 || users[0].Username != "first" {
+		t.Errorf("Unexpected first user %+v", users[0])
+	}
+	if users[1].ID != 2 || users[1].Username != "last" || users[1].Email != "last@test.com" {
+		t.Errorf("Unexpected second user %+v", users[1])
+	}
+	if users[1].CreatedBy == nil || *users[1].CreatedBy != 1 {
+		t.Errorf("Expected created_by to follow the renumbered creator, got %v", users[1].CreatedBy)
+	}
+	if entries, _ := store.ListAudit(ctx, 3); len(entries) != 0 {
+		t.Errorf("Expected audit entries to move off the old id, got %d", len(entries))
+	}
+
+	next := &User{Username: "next", Email: "next@test.com"}
+	_ = store.Create(ctx, next)
+	if next.ID != 3 {
+		t.Errorf("Expected next id 3, got %d", next.ID)
+	}
+}
@@ -0,0 +1,80 @@
+package userstore
+
+import (
+	"context"
+)
+
+// ReplicaStore wraps a primary Store and sends the common reads to a replica
+// everything else, writes included, goes to the primary
+// a read the replica fails, ErrUserNotFound included since the replica
+// may lag behind, is retried on the primary
+// Close only closes the primary, the caller owns the replica
+type ReplicaStore struct {
+	Store
+	replica Store
+}
+
+// NewReplicaStore reads from replica and writes to primary
+func NewReplicaStore(primary, replica Store) *ReplicaStore {
+	return &ReplicaStore{Store: primary, replica: replica}
+}
+
+// read runs fn on the replica and on the primary when that fails
+// a cancelled or expired ctx is returned as is
+func read[T any](ctx context.Context, r *ReplicaStore, fn func(Store) (T, error)) (T, error) {
+	v, err := fn(r.replica)
+	if err == nil || ctx.Err() != nil {
+		return v, err
+	}
+	return fn(r.Store)
+}
+
+func (r *ReplicaStore) GetById(ctx context.Context, id int64) (*User, error) {
+	return read(ctx, r, func(s Store) (*User, error) { return s.GetById(ctx, id) })
+}
+
+func (r *ReplicaStore) GetByEmail(ctx context.Context, email string) (*User, error) {
+	return read(ctx, r, func(s Store) (*User, error) { return s.GetByEmail(ctx, email) })
+}
+
+func (r *ReplicaStore) ListAll(ctx context.Context) ([]User, error) {
+	return read(ctx, r, func(s Store) ([]User, error) { return s.ListAll(ctx) })
+}
+
+func (r *ReplicaStore) List(ctx context.Context, limit, offset int) ([]User, error) {
+	return read(ctx, r, func(s Store) ([]User, error) { return s.List(ctx, limit, offset) })
+}
+
+func (r *ReplicaStore) Page(ctx context.Context, limit, offset int) (PageResult, error) {
+	return read(ctx, r, func(s Store) (PageResult, error) { return s.Page(ctx, limit, offset) })
+}
+
+func (r *ReplicaStore) Count(ctx context.Context) (int64, error) {
+	return read(ctx, r, func(s Store) (int64, error) { return s.Count(ctx) })
+}
+
+func (r *ReplicaStore) Query(ctx context.Context, f UserFilter) ([]User, error) {
+	return read(ctx, r, func(s Store) ([]User, error) { return s.Query(ctx, f) })
+}
+
+func (r *ReplicaStore) QueryCount(ctx context.Context, f UserFilter) (int64, error) {
+	return read(ctx, r, func(s Store) (int64, error) { return s.QueryCount(ctx, f) })
+}
+
+func (r *ReplicaStore) FullTextSearch(ctx context.Context, query string) ([]User, error) {
+	return read(ctx, r, func(s Store) ([]User, error) { return s.FullTextSearch(ctx, query) })
+}
+
+// searchPage is what Search returns
+type searchPage struct {
+	users []User
+	total int64
+}
+
+func (r *ReplicaStore) Search(ctx context.Context, query string, limit, offset int) ([]User, int64, error) {
+	p, err := read(ctx, r, func(s Store) (searchPage, error) {
+		users, total, err := s.Search(ctx, query, limit, offset)
+		return searchPage{users: users, total: total}, err
+	})
+	return p.users, p.total, err
+}
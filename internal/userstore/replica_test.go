@@ -0,0 +1,139 @@
+package userstore
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// recordingStore records the calls that reach it and can fail reads
+type recordingStore struct {
+	Store
+	calls []string
+	down  bool
+}
+
+var errReplicaDown = errors.New("replica down")
+
+func (r *recordingStore) GetById(ctx context.Context, id int64) (*User, error) {
+	r.calls = append(r.calls, "GetById")
+	if r.down {
+		return nil, errReplicaDown
+	}
+	return r.Store.GetById(ctx, id)
+}
+
+func (r *recordingStore) ListAll(ctx context.Context) ([]User, error) {
+	r.calls = append(r.calls, "ListAll")
+	return r.Store.ListAll(ctx)
+}
+
+func (r *recordingStore) Count(ctx context.Context) (int64, error) {
+	r.calls = append(r.calls, "Count")
+	return r.Store.Count(ctx)
+}
+
+func (r *recordingStore) Search(ctx context.Context, query string, limit, offset int) ([]User, int64, error) {
+	r.calls = append(r.calls, "Search")
+	return r.Store.Search(ctx, query, limit, offset)
+}
+
+func (r *recordingStore) Create(ctx context.Context, user *User) error {
+	r.calls = append(r.calls, "Create")
+	return r.Store.Create(ctx, user)
+}
+
+// Read replica test
+func TestReplicaStore(t *testing.T) {
+	ctx := context.Background()
+	primary := &recordingStore{Store: StoreTest(t)}
+	replica := &recordingStore{Store: StoreTest(t)}
+	store := NewReplicaStore(primary, replica)
+
+	// the replica is synced out of band, here by writing to it directly
+	u := &User{Username: "copied", Email: "copied@test.com"}
+	if err := store.Create(ctx, u); err != nil {
+		t.Fatalf("Create failed : %v", err)
+	}
+	_ = replica.Store.Create(ctx, &User{Username: "copied", Email: "copied@test.com"})
+
+	if _, err := store.GetById(ctx, u.ID); err != nil {
+		t.Fatalf("GetById failed : %v", err)
+	}
+	_, _ = store.ListAll(ctx)
+	_, _ = store.Count(ctx)
+	_, _, _ = store.Search(ctx, "copied", 10, 0)
+
+	want := []string{"GetById", "ListAll", "Count", "Search"}
+	if len(replica.calls) != len(want) {
+		t.Fatalf("Expected replica calls %v, got %v", want, replica.calls)
+	}
+	for i := range want {
+		if replica.calls[i] != want[i] {
+			t.Errorf("Expected replica calls %v, got %v", want, replica.calls)
+			break
+		}
+	}
+	if len(primary.calls) != 1 || primary.calls[0] != "Create" {
+		t.Errorf("Expected only Create on the primary, got %v", primary.calls)
+	}
+
+	// a failing replica falls back to the primary
+	replica.down = true
+	got, err := store.GetById(ctx, u.ID)
+	if err != nil || got.Username != "copied" {
+		t.Errorf("Expected the user from the primary, got %+v, %v", got, err)
+	}
+	if n := len(primary.calls); n != 2 || primary.calls[n-1] != "GetById" {
+		t.Errorf("Expected GetById on the primary, got %v", primary.calls)
+	}
+}
+
+// Read replica option test
+func TestWithReadReplica(t *testing.T) {
+	ctx := context.Background()
+	replica := &recordingStore{Store: StoreTest(t)}
+	store := storeWithOptions(t, WithReadReplica(replica))
+	if _, ok := store.(*ReplicaStore); !ok {
+		t.Fatalf("Expected a ReplicaStore, got %T", store)
+	}
+	if !store.Config().ReadReplica {
+		t.Errorf("Expected ReadReplica in the config")
+	}
+	_, _ = store.Count(ctx)
+	if len(replica.calls) != 1 {
+		t.Errorf("Expected Count on the replica, got %v", replica.calls)
+	}
+
+	// transactions run on the primary
+	if err := WithTx(ctx, store, nil, func(tx Store) error {
+		return tx.Create(ctx, &User{Username: "tx", Email: "tx@test.com"})
+	}); err != nil {
+		t.Fatalf("WithTx failed : %v", err)
+	}
+}
+
+// Clone of a replica backed store test
+func TestCloneToDropsReplica(t *testing.T) {
+	ctx := context.Background()
+	replica := &recordingStore{Store: StoreTest(t)}
+	store := storeAt(t, filepath.Join(t.TempDir(), "primary.db"), WithReadReplica(replica))
+	_ = store.Create(ctx, &User{Username: "cloned", Email: "cloned@test.com"})
+
+	clone, err := store.CloneTo(ctx, filepath.Join(t.TempDir(), "clone.db"))
+	if err != nil {
+		t.Fatalf("CloneTo failed : %v", err)
+	}
+	defer clone.Close()
+	if _, ok := clone.(*ReplicaStore); ok || clone.Config().ReadReplica {
+		t.Errorf("Expected the clone without the replica")
+	}
+	// the replica is empty, the clone has the user
+	if n, err := clone.Count(ctx); err != nil || n != 1 {
+		t.Errorf("Expected 1 user read from the clone, got %d, %v", n, err)
+	}
+	if len(replica.calls) != 0 {
+		t.Errorf("Expected no reads on the replica, got %v", replica.calls)
+	}
+}
@@ -0,0 +1,120 @@
+package userstore
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// retryAttempts is how many times a busy transaction is tried in total
+const retryAttempts = 5
+
+// isBusy reports whether err means another connection holds the lock
+// even after busy_timeout ran out
+func isBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+}
+
+// retry runs fn again while it fails with a busy error
+// retries are off until WithRetryBackoff is set
+func (s *sqlStore) retry(ctx context.Context, fn func() error) error {
+	err := fn()
+	if s.cfg.retryBase <= 0 {
+		return err
+	}
+	for attempt := 1; attempt < retryAttempts && isBusy(err); attempt++ {
+		if werr := s.sleep(ctx, s.backoff(attempt)); werr != nil {
+			return werr
+		}
+		err = fn()
+	}
+	return err
+}
+
+// openDB opens the pool and its first connection
+// so a bad path or a locked file fails here and not on the first query
+func openDB(c driver.Connector) (*sql.DB, error) {
+	db := sql.OpenDB(c)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// open runs the opener until it succeeds, fails with a non busy error
+// or used up the attempts of WithOpenRetry
+// another process holding the file during startup is the busy case
+func (s *sqlStore) open(c driver.Connector) (*sql.DB, error) {
+	opener := s.cfg.opener
+	if opener == nil {
+		opener = openDB
+	}
+	db, err := opener(c)
+	for attempt := 1; attempt < s.cfg.openAttempts && isBusy(err); attempt++ {
+		if werr := s.sleep(context.Background(), s.cfg.openBackoff*time.Duration(attempt)); werr != nil {
+			return nil, werr
+		}
+		db, err = opener(c)
+	}
+	return db, err
+}
+
+// backoff is base * attempt plus a random jitter below base, capped at max
+// the jitter keeps writers that failed together from retrying together
+func (s *sqlStore) backoff(attempt int) time.Duration {
+	base := s.cfg.retryBase
+	d := base*time.Duration(attempt) + time.Duration(s.jitter(int64(base)))
+	if s.cfg.retryMax > 0 && d > s.cfg.retryMax {
+		d = s.cfg.retryMax
+	}
+	return d
+}
+
+func (s *sqlStore) jitter(n int64) int64 {
+	if n <= 0 {
+		return 0
+	}
+	if s.cfg.jitter != nil {
+		return s.cfg.jitter.Int64N(n)
+	}
+	return rand.Int64N(n)
+}
+
+// lockedSource lets writers that retry at the same time share one source
+// a rand.Source is not safe for concurrent use
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source
+}
+
+func (l *lockedSource) Uint64() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.src.Uint64()
+}
+
+// sleep waits d or until ctx is done
+func (s *sqlStore) sleep(ctx context.Context, d time.Duration) error {
+	if s.cfg.sleep != nil {
+		return s.cfg.sleep(ctx, d)
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
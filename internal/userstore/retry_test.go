@@ -0,0 +1,150 @@
+package userstore
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"math/rand/v2"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Backoff bounds test
+func TestRetryBackoffBounds(t *testing.T) {
+	base, max := 10*time.Millisecond, 35*time.Millisecond
+	store := storeWithOptions(t,
+		WithRetryBackoff(base, max),
+		WithJitterSource(rand.NewPCG(1, 2)),
+	).(*sqlStore)
+
+	for attempt := 1; attempt <= 4; attempt++ {
+		d := store.backoff(attempt)
+		low := base * time.Duration(attempt)
+		if low > max {
+			low = max
+		}
+		if d < low || d > max || d >= low+base {
+			t.Errorf("attempt %d: delay %v outside [%v, %v]", attempt, d, low, min(low+base, max))
+		}
+	}
+}
+
+// Seeded jitter is repeatable test
+func TestRetryJitterSeeded(t *testing.T) {
+	a := storeWithOptions(t, WithRetryBackoff(time.Second, time.Minute), WithJitterSource(rand.NewPCG(7, 7))).(*sqlStore)
+	b := storeWithOptions(t, WithRetryBackoff(time.Second, time.Minute), WithJitterSource(rand.NewPCG(7, 7))).(*sqlStore)
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		if a.backoff(attempt) != b.backoff(attempt) {
+			t.Fatal("Expected the same seed to give the same delays")
+		}
+	}
+}
+
+// Retry on busy test
+func TestRetryOnBusy(t *testing.T) {
+	store := storeWithOptions(t,
+		WithRetryBackoff(time.Millisecond, 10*time.Millisecond),
+		WithJitterSource(rand.NewPCG(1, 1)),
+	).(*sqlStore)
+
+	var waited []time.Duration
+	store.cfg.sleep = func(ctx context.Context, d time.Duration) error {
+		waited = append(waited, d)
+		return nil
+	}
+
+	calls := 0
+	err := store.retry(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return sqlite3.Error{Code: sqlite3.ErrBusy}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected retry to succeed, got %v", err)
+	}
+	if calls != 3 || len(waited) != 2 {
+		t.Errorf("Expected 3 calls and 2 waits, got %d and %d", calls, len(waited))
+	}
+}
+
+// Shared jitter source with parallel busy writers test
+// run with -race, the seeded source is shared by every retrying writer
+func TestRetryJitterConcurrent(t *testing.T) {
+	store := storeWithOptions(t,
+		WithRetryBackoff(time.Millisecond, 10*time.Millisecond),
+		WithJitterSource(rand.NewPCG(3, 3)),
+	).(*sqlStore)
+	store.cfg.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			calls := 0
+			err := store.retry(context.Background(), func() error {
+				calls++
+				if calls < retryAttempts {
+					return sqlite3.Error{Code: sqlite3.ErrBusy}
+				}
+				return nil
+			})
+			if err != nil {
+				t.Errorf("Expected retry to succeed, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Open retry on a transient failure test
+func TestOpenRetry(t *testing.T) {
+	calls := 0
+	var waited []time.Duration
+	flaky := func(c *config) {
+		c.opener = func(conn driver.Connector) (*sql.DB, error) {
+			calls++
+			if calls < 3 {
+				return nil, sqlite3.Error{Code: sqlite3.ErrBusy}
+			}
+			return openDB(conn)
+		}
+		c.sleep = func(ctx context.Context, d time.Duration) error {
+			waited = append(waited, d)
+			return nil
+		}
+	}
+
+	store := storeWithOptions(t, WithOpenRetry(5, time.Millisecond), flaky)
+	if err := store.Create(context.Background(), &User{Username: "r", Email: "r@test.com"}); err != nil {
+		t.Fatalf("Create after retried open failed : %v", err)
+	}
+	if calls != 3 || len(waited) != 2 || waited[1] != 2*time.Millisecond {
+		t.Errorf("Expected 3 opens and 2 growing waits, got %d %v", calls, waited)
+	}
+}
+
+// Open retry fails fast on permanent errors test
+func TestOpenRetryPermanentError(t *testing.T) {
+	calls := 0
+	counting := func(c *config) {
+		c.opener = func(conn driver.Connector) (*sql.DB, error) {
+			calls++
+			return openDB(conn)
+		}
+	}
+
+	_, err := NewDb("/no/such/dir/users.db", WithOpenRetry(5, time.Millisecond), counting)
+	if err == nil {
+		t.Fatal("Expected an error for a bad path")
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 open attempt, got %d", calls)
+	}
+}
@@ -0,0 +1,73 @@
+package userstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// UpdateRoleMany sets role on every user in ids with one UPDATE
+// and returns how many users actually changed
+// an audit entry is written for each changed user, soft deleted users are skipped
+func (s *sqlStore) UpdateRoleMany(ctx context.Context, ids []int64, role string) (int64, error) {
+	if s.cfg.readOnly {
+		return 0, ErrReadOnly
+	}
+	if !validRoles[role] {
+		return 0, ErrInvalidRole
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+
+	args := make([]any, 0, len(ids)+4)
+	args = append(args, role, s.now().UTC(), role, s.cfg.tenant)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	var changed int64
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		// users already in the role are left alone and not counted
+		query := `UPDATE users SET role = ?, updated_at = ? WHERE role <> ? AND tenant_id = ? AND deleted_at IS NULL AND id IN (` + placeholders(len(ids)) + `) RETURNING id`
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to update roles : %w", err)
+		}
+		var updated []int64
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan id : %w", err)
+			}
+			updated = append(updated, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error during rows iteration : %w", err)
+		}
+
+		// only rows the UPDATE returned are read so a snapshot is never of a deleted user
+		for _, id := range updated {
+			stored, err := s.readUserTx(ctx, tx, id)
+			if err != nil {
+				return err
+			}
+			if stored.DeletedAt != nil {
+				return ErrUserNotFound
+			}
+			if err := s.writeAudit(ctx, tx, AuditActionUpdate, stored); err != nil {
+				return err
+			}
+		}
+		changed = int64(len(updated))
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return changed, nil
+}
@@ -0,0 +1,85 @@
+package userstore
+
+import (
+	"context"
+	"testing"
+)
+
+// Bulk role update test
+func TestUpdateRoleMany(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	var ids []int64
+	for _, name := range []string{"r1", "r2", "r3"} {
+		u := &User{Username: name, Email: name + "@test.com"}
+		_ = store.Create(ctx, u)
+		if u.Role != RoleUser {
+			t.Fatalf("Expected default role user, got %q", u.Role)
+		}
+		ids = append(ids, u.ID)
+	}
+
+	n, err := store.UpdateRoleMany(ctx, ids[:2], RoleAdmin)
+	if err != nil {
+		t.Fatalf("UpdateRoleMany failed : %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 changed, got %d", n)
+	}
+
+	users, _ := store.GetMany(ctx, ids)
+	if users[ids[0]].Role != RoleAdmin || users[ids[1]].Role != RoleAdmin || users[ids[2]].Role != RoleUser {
+		t.Errorf("Unexpected roles %s %s %s", users[ids[0]].Role, users[ids[1]].Role, users[ids[2]].Role)
+	}
+
+	// already admin, nothing changes
+	if n, _ := store.UpdateRoleMany(ctx, ids[:1], RoleAdmin); n != 0 {
+		t.Errorf("Expected 0 changed, got %d", n)
+	}
+	if n, err := store.UpdateRoleMany(ctx, nil, RoleAdmin); n != 0 || err != nil {
+		t.Errorf("Expected empty list to be a no-op, got %d %v", n, err)
+	}
+}
+
+// Invalid role test
+func TestInvalidRole(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	if _, err := store.UpdateRoleMany(ctx, []int64{1}, "root"); err != ErrInvalidRole {
+		t.Errorf("Expected invalid role error, got %v", err)
+	}
+	if err := store.Create(ctx, &User{Username: "x", Email: "x@test.com", Role: "root"}); err != ErrInvalidRole {
+		t.Errorf("Expected invalid role error on create, got %v", err)
+	}
+}
+
+// Bulk role update skips soft deleted users test
+func TestUpdateRoleManySoftDeleted(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+	live := &User{Username: "live", Email: "live@test.com"}
+	gone := &User{Username: "gone", Email: "gone@test.com"}
+	_ = store.Create(ctx, live)
+	_ = store.Create(ctx, gone)
+	if err := store.SoftDelete(ctx, gone.ID); err != nil {
+		t.Fatalf("SoftDelete failed : %v", err)
+	}
+	before, _ := store.ListAudit(ctx, gone.ID)
+
+	n, err := store.UpdateRoleMany(ctx, []int64{live.ID, gone.ID}, RoleAdmin)
+	if err != nil {
+		t.Fatalf("UpdateRoleMany failed : %v", err)
+	}
+	if n != 1 {
+		t.Errorf("Expected 1 changed, got %d", n)
+	}
+	got, _ := store.GetByIdIncludingDeleted(ctx, gone.ID)
+	if got == nil || got.Role != RoleUser {
+		t.Errorf("Expected the soft deleted user to keep its role, got %+v", got)
+	}
+	if after, _ := store.ListAudit(ctx, gone.ID); len(after) != len(before) {
+		t.Errorf("Expected no audit entry for the soft deleted user, got %d new", len(after)-len(before))
+	}
+}
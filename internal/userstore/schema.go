@@ -0,0 +1,111 @@
+package userstore
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// usersSchema is every column of the users table after all migrations
+// with its declared type
+var usersSchema = []struct {
+	name, typ string
+}{
+	{"id", "INTEGER"},
+	{"tenant_id", "INTEGER"},
+	{"username", "TEXT"},
+	{"email", "TEXT"},
+	{"created_at", "DATETIME"},
+	{"last_login_at", "DATETIME"},
+	{"created_by", "INTEGER"},
+	{"uuid", "TEXT"},
+	{"role", "TEXT"},
+	{"deleted_at", "DATETIME"},
+	{"email_verified", "BOOLEAN"},
+	{"canonical_email", "TEXT"},
+	{"updated_at", "DATETIME"},
+	{"email_hash", "TEXT"},
+	{"metadata", "TEXT"},
+	{"email_lower", "TEXT"},
+	{"display_name", "TEXT"},
+}
+
+// CheckSchema compares the users table with the columns this version expects
+// and returns ErrSchemaMismatch listing missing, extra and wrongly typed columns
+// types are compatible when sqlite gives them the same affinity
+func (s *sqlStore) CheckSchema(ctx context.Context) error {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	rows, err := s.conn().QueryContext(ctx, `SELECT name, type FROM pragma_table_info('users')`)
+	if err != nil {
+		return fmt.Errorf("failed to read users columns : %w", err)
+	}
+	defer rows.Close()
+	actual := map[string]string{}
+	var order []string
+	for rows.Next() {
+		var name, typ string
+		if err := rows.Scan(&name, &typ); err != nil {
+			return fmt.Errorf("failed to scan column : %w", err)
+		}
+		actual[name] = typ
+		order = append(order, name)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error during rows iteration : %w", err)
+	}
+	if len(actual) == 0 {
+		return ErrSchemaMissing
+	}
+
+	var missing, extra, wrong []string
+	for _, c := range usersSchema {
+		typ, ok := actual[c.name]
+		if !ok {
+			missing = append(missing, c.name)
+			continue
+		}
+		if affinity(typ) != affinity(c.typ) {
+			wrong = append(wrong, fmt.Sprintf("%s is %s, expected %s", c.name, typ, c.typ))
+		}
+	}
+	for _, name := range order {
+		known := slices.ContainsFunc(usersSchema, func(c struct{ name, typ string }) bool {
+			return c.name == name
+		})
+		if !known {
+			extra = append(extra, name)
+		}
+	}
+
+	var problems []string
+	if len(missing) > 0 {
+		problems = append(problems, "missing columns "+strings.Join(missing, ", "))
+	}
+	if len(extra) > 0 {
+		problems = append(problems, "extra columns "+strings.Join(extra, ", "))
+	}
+	problems = append(problems, wrong...)
+	if len(problems) > 0 {
+		return fmt.Errorf("%w : %s", ErrSchemaMismatch, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// affinity is the type affinity sqlite derives from a declared column type
+// https://www.sqlite.org/datatype3.html#determination_of_column_affinity
+func affinity(typ string) string {
+	typ = strings.ToUpper(typ)
+	switch {
+	case strings.Contains(typ, "INT"):
+		return "INTEGER"
+	case strings.Contains(typ, "CHAR"), strings.Contains(typ, "CLOB"), strings.Contains(typ, "TEXT"):
+		return "TEXT"
+	case strings.Contains(typ, "BLOB"), typ == "":
+		return "BLOB"
+	case strings.Contains(typ, "REAL"), strings.Contains(typ, "FLOA"), strings.Contains(typ, "DOUB"):
+		return "REAL"
+	}
+	return "NUMERIC"
+}
@@ -0,0 +1,41 @@
+package userstore
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Check schema test
+func TestCheckSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.db")
+	store := storeAt(t, path)
+	ctx := context.Background()
+
+	if err := store.CheckSchema(ctx); err != nil {
+		t.Fatalf("Expected a fresh store to match, got %v", err)
+	}
+
+	db := store.(*sqlStore).db
+	if _, err := db.Exec(`ALTER TABLE users DROP COLUMN metadata`); err != nil {
+		t.Fatalf("failed to drop column : %v", err)
+	}
+	if _, err := db.Exec(`ALTER TABLE users ADD COLUMN nickname TEXT`); err != nil {
+		t.Fatalf("failed to add column : %v", err)
+	}
+	err := store.CheckSchema(ctx)
+	if !errors.Is(err, ErrSchemaMismatch) {
+		t.Fatalf("Expected ErrSchemaMismatch, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "missing columns metadata") || !strings.Contains(err.Error(), "extra columns nickname") {
+		t.Errorf("Expected the missing and extra columns in %q", err)
+	}
+	_ = store.Close()
+
+	// the startup check refuses to open it
+	if _, err := NewDb(path, WithSchemaCheck(true)); !errors.Is(err, ErrSchemaMismatch) {
+		t.Errorf("Expected ErrSchemaMismatch at startup, got %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package userstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SoftDelete marks the user as deleted without removing the row
+// normal reads skip it while ListDeleted and GetByIdIncludingDeleted still see it
+// Delete removes soft deleted users for good
+func (s *sqlStore) SoftDelete(ctx context.Context, id int64) error {
+	if s.cfg.readOnly {
+		return ErrReadOnly
+	}
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		query := `UPDATE users SET deleted_at = ? WHERE id = ? AND tenant_id = ? AND deleted_at IS NULL`
+		result, err := tx.ExecContext(ctx, query, s.now().UTC(), id, s.cfg.tenant)
+		if err != nil {
+			return fmt.Errorf("failed to soft delete user : %w", err)
+		}
+		count, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrUserNotFound
+		}
+
+		stored, err := s.readUserTx(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		return s.writeAudit(ctx, tx, AuditActionSoftDelete, stored)
+	})
+}
+
+// ListDeleted returns only the soft deleted users ordered by id
+func (s *sqlStore) ListDeleted(ctx context.Context) ([]User, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	query := `SELECT ` + userColumns + ` FROM users WHERE tenant_id = ? AND deleted_at IS NOT NULL ORDER BY id`
+	rows, err := s.conn().QueryContext(ctx, query, s.cfg.tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted users : %w", err)
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		u, err := s.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user : %w", err)
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration : %w", err)
+	}
+	return users, nil
+}
+
+// GetByIdIncludingDeleted is GetById that also returns soft deleted users
+func (s *sqlStore) GetByIdIncludingDeleted(ctx context.Context, id int64) (*User, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = ? AND tenant_id = ?`
+	user, err := s.scan(s.conn().QueryRowContext(ctx, query, id, s.cfg.tenant))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("Failed to get user: %w", err)
+	}
+	return &user, nil
+}
+
+// PurgeDeleted removes users soft deleted more than olderThan ago for good
+// and returns how many were removed, now comes from the store clock
+// like Delete, users they created are detached and an audit entry is written for each
+func (s *sqlStore) PurgeDeleted(ctx context.Context, olderThan time.Duration) (int64, error) {
+	if s.cfg.readOnly {
+		return 0, ErrReadOnly
+	}
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	cutoff := s.now().Add(-olderThan).UTC()
+	var purged int64
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		purged = 0
+		where := ` WHERE tenant_id = ? AND deleted_at IS NOT NULL AND deleted_at < ?`
+		// snapshot the rows before they are gone
+		rows, err := tx.QueryContext(ctx, `SELECT `+userColumns+` FROM users`+where+` ORDER BY id`, s.cfg.tenant, cutoff)
+		if err != nil {
+			return fmt.Errorf("failed to list deleted users : %w", err)
+		}
+		var users []User
+		for rows.Next() {
+			u, err := s.scan(rows)
+			if err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan user : %w", err)
+			}
+			users = append(users, u)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error during rows iteration : %w", err)
+		}
+		if len(users) == 0 {
+			return nil
+		}
+
+		// created_by has no ON DELETE action so detach children first
+		query := `UPDATE users SET created_by = NULL WHERE created_by IN (SELECT id FROM users` + where + `)`
+		if _, err := tx.ExecContext(ctx, query, s.cfg.tenant, cutoff); err != nil {
+			return fmt.Errorf("failed to detach created users : %w", err)
+		}
+		result, err := tx.ExecContext(ctx, `DELETE FROM users`+where, s.cfg.tenant, cutoff)
+		if err != nil {
+			return fmt.Errorf("failed to purge users : %w", err)
+		}
+		if purged, err = result.RowsAffected(); err != nil {
+			return err
+		}
+		for i := range users {
+			if err := s.tombstone(ctx, tx, users[i].ID); err != nil {
+				return err
+			}
+			if err := s.writeAudit(ctx, tx, AuditActionDelete, &users[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
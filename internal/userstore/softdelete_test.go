@@ -0,0 +1,189 @@
+package userstore
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// Soft delete and list deleted test
+func TestSoftDelete(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+	keep := &User{Username: "keep", Email: "keep@test.com"}
+	gone := &User{Username: "gone", Email: "gone@test.com"}
+	_ = store.Create(ctx, keep)
+	_ = store.Create(ctx, gone)
+
+	if err := store.SoftDelete(ctx, gone.ID); err != nil {
+		t.Fatalf("SoftDelete failed : %v", err)
+	}
+	if err := store.SoftDelete(ctx, gone.ID); err != ErrUserNotFound {
+		t.Errorf("Expected not found on second soft delete, got %v", err)
+	}
+
+	users, _ := store.ListAll(ctx)
+	if len(users) != 1 || users[0].ID != keep.ID {
+		t.Errorf("Expected only the active user in ListAll, got %+v", users)
+	}
+	if _, err := store.GetById(ctx, gone.ID); err != ErrUserNotFound {
+		t.Errorf("Expected GetById to hide soft deleted user, got %v", err)
+	}
+
+	deleted, err := store.ListDeleted(ctx)
+	if err != nil {
+		t.Fatalf("ListDeleted failed : %v", err)
+	}
+	if len(deleted) != 1 || deleted[0].ID != gone.ID || deleted[0].DeletedAt == nil {
+		t.Errorf("Expected the soft deleted user, got %+v", deleted)
+	}
+
+	got, err := store.GetByIdIncludingDeleted(ctx, gone.ID)
+	if err != nil {
+		t.Fatalf("GetByIdIncludingDeleted failed : %v", err)
+	}
+	if got.Username != "gone" || got.DeletedAt == nil {
+		t.Errorf("Unexpected user %+v", got)
+	}
+}
+
+// Update of a soft deleted user test
+func TestUpdateSoftDeleted(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+	gone := &User{Username: "gone", Email: "gone@test.com"}
+	_ = store.Create(ctx, gone)
+	if err := store.SoftDelete(ctx, gone.ID); err != nil {
+		t.Fatalf("SoftDelete failed : %v", err)
+	}
+
+	if err := store.Update(ctx, &User{ID: gone.ID, Username: "back", Email: "gone@test.com"}); err != ErrUserNotFound {
+		t.Errorf("Expected Update to not find a soft deleted user, got %v", err)
+	}
+	if _, err := store.UpdateWithDiff(ctx, &User{ID: gone.ID, Username: "back", Email: "gone@test.com"}); err != ErrUserNotFound {
+		t.Errorf("Expected UpdateWithDiff to not find a soft deleted user, got %v", err)
+	}
+	got, _ := store.GetByIdIncludingDeleted(ctx, gone.ID)
+	if got == nil || got.Username != "gone" {
+		t.Errorf("Expected the soft deleted user to be unchanged, got %+v", got)
+	}
+}
+
+// Purge deleted test
+func TestPurgeDeleted(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := storeWithOptions(t, WithClock(clock.Now))
+
+	var users []*User
+	for _, name := range []string{"old", "recent", "active"} {
+		u := &User{Username: name, Email: name + "@test.com"}
+		_ = store.Create(ctx, u)
+		users = append(users, u)
+	}
+	// child keeps existing once its creator is purged
+	child := &User{Username: "child", Email: "child@test.com", CreatedBy: &users[0].ID}
+	_ = store.Create(ctx, child)
+
+	_ = store.SoftDelete(ctx, users[0].ID)
+	clock.now = clock.now.Add(20 * 24 * time.Hour)
+	_ = store.SoftDelete(ctx, users[1].ID)
+	clock.now = clock.now.Add(5 * 24 * time.Hour)
+
+	n, err := store.PurgeDeleted(ctx, 10*24*time.Hour)
+	if err != nil {
+		t.Fatalf("PurgeDeleted failed : %v", err)
+	}
+	if n != 1 {
+		t.Errorf("Expected 1 purged, got %d", n)
+	}
+	if _, err := store.GetByIdIncludingDeleted(ctx, users[0].ID); err != ErrUserNotFound {
+		t.Errorf("Expected old user purged, got %v", err)
+	}
+	if _, err := store.GetByIdIncludingDeleted(ctx, users[1].ID); err != nil {
+		t.Errorf("Expected recent user kept, got %v", err)
+	}
+	got, _ := store.GetById(ctx, child.ID)
+	if got == nil || got.CreatedBy != nil {
+		t.Errorf("Expected child detached, got %+v", got)
+	}
+
+	if n, _ := store.PurgeDeleted(ctx, 10*24*time.Hour); n != 0 {
+		t.Errorf("Expected nothing left to purge, got %d", n)
+	}
+}
+
+// Deletion tombstones test
+func TestDeletionTombstones(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	store := storeWithOptions(t, WithDeletionTombstones(true), WithClock(clock.Now))
+	ctx := context.Background()
+	db := store.(*sqlStore).db
+
+	u := &User{Username: "forget", Email: "forget@test.com"}
+	if err := store.Create(ctx, u); err != nil {
+		t.Fatalf("Create failed : %v", err)
+	}
+	if err := store.Delete(ctx, u.ID); err != nil {
+		t.Fatalf("Delete failed : %v", err)
+	}
+
+	var userID int64
+	var deletedAt time.Time
+	if err := db.QueryRow(`SELECT user_id, deleted_at FROM deletions`).Scan(&userID, &deletedAt); err != nil {
+		t.Fatalf("failed to read tombstone : %v", err)
+	}
+	if userID != u.ID || !deletedAt.Equal(clock.now) {
+		t.Errorf("Expected tombstone for %d at %v, got %d at %v", u.ID, clock.now, userID, deletedAt)
+	}
+
+	rows, err := db.Query(`SELECT name FROM pragma_table_info('deletions')`)
+	if err != nil {
+		t.Fatalf("failed to read columns : %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		_ = rows.Scan(&name)
+		if name != "id" && name != "user_id" && name != "deleted_at" {
+			t.Errorf("Unexpected column %q in deletions", name)
+		}
+	}
+
+	// purged soft deletes leave one too
+	gone := &User{Username: "gone", Email: "gone@test.com"}
+	_ = store.Create(ctx, gone)
+	_ = store.SoftDelete(ctx, gone.ID)
+	clock.now = clock.now.Add(time.Hour)
+	if _, err := store.PurgeDeleted(ctx, time.Minute); err != nil {
+		t.Fatalf("PurgeDeleted failed : %v", err)
+	}
+	var n int
+	_ = db.QueryRow(`SELECT COUNT(*) FROM deletions WHERE user_id = ?`, gone.ID).Scan(&n)
+	if n != 1 {
+		t.Errorf("Expected a tombstone for the purged user, got %d", n)
+	}
+
+	// so do users removed by a merge
+	keep := &User{Username: "keep", Email: "keep@test.com"}
+	dup := &User{Username: "dup", Email: "dup@test.com"}
+	_ = store.Create(ctx, keep)
+	_ = store.Create(ctx, dup)
+	if err := store.MergeUsers(ctx, keep.ID, dup.ID); err != nil {
+		t.Fatalf("MergeUsers failed : %v", err)
+	}
+	_ = db.QueryRow(`SELECT COUNT(*) FROM deletions WHERE user_id = ?`, dup.ID).Scan(&n)
+	if n != 1 {
+		t.Errorf("Expected a tombstone for the merged user, got %d", n)
+	}
+
+	// off by default
+	plain := StoreTest(t)
+	v := &User{Username: "plain", Email: "plain@test.com"}
+	_ = plain.Create(ctx, v)
+	_ = plain.Delete(ctx, v.ID)
+	_ = plain.(*sqlStore).db.QueryRow(`SELECT COUNT(*) FROM deletions`).Scan(&n)
+	if n != 0 {
+		t.Errorf("Expected no tombstones without the option, got %d", n)
+	}
+}
@@ -3,86 +3,492 @@ package userstore
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
 	"strings"
-	_ "github.com/mattn/go-sqlite3"
+	"sync"
+	"time"
+	"github.com/mattn/go-sqlite3"
 )
 
+// userColumns is the column list every user query selects
+// it must stay in the order scanUser reads them
+const userColumns = `id, username, email, created_at, last_login_at, created_by, uuid, role, deleted_at, email_verified, updated_at, metadata, display_name`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row rowScanner) (User, error) {
+	var u User
+	// rows written with raw sql may have a NULL created_at
+	// it reads as the zero time
+	var createdAt sql.NullTime
+	var lastLogin sql.NullTime
+	var createdBy sql.NullInt64
+	var uuid sql.NullString
+	var deletedAt sql.NullTime
+	var updatedAt sql.NullTime
+	var metadata sql.NullString
+	var displayName sql.NullString
+	err := row.Scan(&u.ID, &u.Username, &u.Email, &createdAt, &lastLogin, &createdBy, &uuid, &u.Role, &deletedAt, &u.EmailVerified, &updatedAt, &metadata, &displayName)
+	if err != nil {
+		return u, err
+	}
+	u.CreatedAt = createdAt.Time
+	u.UUID = uuid.String
+	if lastLogin.Valid {
+		u.LastLoginAt = &lastLogin.Time
+	}
+	if createdBy.Valid {
+		u.CreatedBy = &createdBy.Int64
+	}
+	if deletedAt.Valid {
+		u.DeletedAt = &deletedAt.Time
+	}
+	if updatedAt.Valid {
+		u.UpdatedAt = &updatedAt.Time
+	}
+	if displayName.Valid {
+		u.DisplayName = &displayName.String
+	}
+	if metadata.Valid {
+		if err := json.Unmarshal([]byte(metadata.String), &u.Metadata); err != nil {
+			return u, fmt.Errorf("user %d : %w : %v", u.ID, ErrInvalidMetadata, err)
+		}
+	}
+	return u, nil
+}
+
+// connector opens sqlite connections through a driver with a ConnectHook
+type connector struct {
+	dsn    string
+	driver *sqlite3.SQLiteDriver
+	// logger wraps every connection when set, see WithQueryLogger
+	logger func(sql string, args []any, dur time.Duration, err error)
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil || c.logger == nil {
+		return conn, err
+	}
+	return &loggingConn{SQLiteConn: conn.(*sqlite3.SQLiteConn), log: c.logger}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return c.driver
+}
+
 type sqlStore struct {
-	db *sql.DB
+	db  *sql.DB
+	cfg config
+	// limiter is nil unless WithCreateRateLimit is set
+	limiter *domainLimiter
+	// crypt is nil unless WithEmailEncryption is set
+	crypt *emailCipher
+	// tx is set by BeginStore, every operation then runs inside it
+	tx *sql.Tx
+	// release ends the connection setup of tx, see beginTx
+	release func()
+	// closeOnce makes Close safe to call more than once
+	// a pointer so BeginStore can copy the store
+	closeOnce *sync.Once
 }
 
-func NewDb(dbPath string) (Store, error) {
-	// create sqlite db
-	db, err := sql.Open("sqlite3", dbPath)
+func NewDb(dbPath string, opts ...Option) (Store, error) {
+	cfg := config{path: dbPath}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.busyTimeout <= 0 {
+		cfg.busyTimeout = defaultBusyTimeout
+	}
+	if cfg.maxPageSize <= 0 {
+		cfg.maxPageSize = defaultMaxPageSize
+	}
+	if cfg.journalMode == "" {
+		cfg.journalMode = defaultJournalMode
+	}
+	if !slices.Contains(journalModes, cfg.journalMode) {
+		return nil, ErrInvalidJournalMode
+	}
+	if cfg.idOffset != nil && *cfg.idOffset <= 0 {
+		return nil, ErrInvalidIDOffset
+	}
+	// synchronous = NORMAL can lose the last commits on power loss
+	// in the rollback journal modes, FULL does not
+	synchronous := "NORMAL"
+	if cfg.journalMode != "WAL" {
+		synchronous = "FULL"
+	}
+
+	dsn, err := buildDSN(dbPath, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database : %w", err)
+		return nil, err
 	}
+
 	// PRAGMA is sqlite settings
 	pragmas := []string{
-		// journal_mode - WAL : write ahead logging
-		// db changes first write in WAL files and then commit to db
-		// its persistence and have better concurrent read/write
-		"PRAGMA	journal_mode = WAL;",
-		// synchronous settings used in wal mode
 		// synchronous controls the fsync operations
-		"PRAGMA synchronous = NORMAL;",
+		"PRAGMA synchronous = " + synchronous + ";",
 		// by default sqlite does not check foreign_keys
 		// with this settings it does
 		"PRAGMA foreign_keys = ON;",
-		// if the db is lock it waits for 5 sec
+		// if the db is lock it waits for busyTimeout, 5 sec by default
 		// its good for concurrency and prevent the database is locked error
 		//  so it is future-proof
-		"PRAGMA busy_timeout=5000;",
+		fmt.Sprintf("PRAGMA busy_timeout=%d;", cfg.busyTimeout.Milliseconds()),
+	}
+	// journal_mode - WAL by default : write ahead logging
+	// db changes first write in WAL files and then commit to db
+	// its persistence and have better concurrent read/write
+	// the mode is stored in the file, a read-only connection can not set it
+	// and an empty file would fail here before the schema check
+	if !cfg.readOnly {
+		pragmas = append([]string{"PRAGMA journal_mode = " + cfg.journalMode + ";"}, pragmas...)
+	}
+	if cfg.autoCheckpoint != 0 {
+		pragmas = append(pragmas, fmt.Sprintf("PRAGMA wal_autocheckpoint = %d;", cfg.autoCheckpoint))
 	}
-	for _, p := range pragmas {
-		if _, err := db.Exec(p); err != nil {
-			return nil, fmt.Errorf("failed to apply pragma %s: %w", p, err)
+	exec := cfg.pragmaExec
+	if exec == nil {
+		exec = func(conn *sqlite3.SQLiteConn, query string) error {
+			_, err := conn.Exec(query, nil)
+			return err
 		}
 	}
+	// a failed optional pragma is only warned about once per store
+	var warnOnce sync.Once
+	// most pragmas only affect the connection they run on
+	// so they are applied to every connection the pool opens
+	drv := &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			for _, p := range pragmas {
+				err := exec(conn, p)
+				if err == nil {
+					continue
+				}
+				if cfg.strictPragmas || !optionalPragma(p) {
+					return fmt.Errorf("failed to apply pragma %s: %w", p, err)
+				}
+				warnOnce.Do(func() {
+					slog.Warn("userstore: pragma not applied, continuing without it", "pragma", p, "err", err)
+				})
+			}
+			return nil
+		},
+	}
 
-	s := &sqlStore{db: db}
+	// create sqlite db
+	s := &sqlStore{cfg: cfg, closeOnce: &sync.Once{}}
+	if cfg.emailKey != nil {
+		crypt, err := newEmailCipher(cfg.emailKey)
+		if err != nil {
+			return nil, err
+		}
+		s.crypt = crypt
+	}
+	db, err := s.open(&connector{dsn: dsn, driver: drv, logger: cfg.queryLogger})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database : %w", err)
+	}
+	s.db = db
+	if cfg.rateLimit > 0 {
+		s.limiter = newDomainLimiter(cfg.rateLimit, cfg.rateWindow)
+	}
+	// a read-only store can not create tables
+	// so the schema has to be there already
+	if cfg.readOnly {
+		if err := s.checkTables(); err != nil {
+			db.Close()
+			return nil, err
+		}
+		if err := s.startupSchemaCheck(); err != nil {
+			db.Close()
+			return nil, err
+		}
+		return s.withReplica(), nil
+	}
 	if err := s.migrate(); err != nil {
 		return nil, err
 	}
+	if err := s.applyIDOffset(context.Background(), db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := s.applyEmailScope(context.Background(), db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if cfg.fullText {
+		if err := s.setupFullText(); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	if err := s.startupSchemaCheck(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s.withReplica(), nil
+}
+
+// withReplica wraps s in a ReplicaStore when WithReadReplica is set
+func (s *sqlStore) withReplica() Store {
+	if s.cfg.replica == nil {
+		return s
+	}
+	return NewReplicaStore(s, s.cfg.replica)
+}
+
+// startupSchemaCheck runs CheckSchema when WithSchemaCheck is set
+func (s *sqlStore) startupSchemaCheck() error {
+	if !s.cfg.checkSchema {
+		return nil
+	}
+	return s.CheckSchema(context.Background())
+}
 
-	return s, nil
+// optionalPragma reports a pragma the store can run without
+// some sqlite builds lack foreign keys and some filesystems lack WAL
+// without foreign keys ErrCreatorNotFound is not detected and
+// deleting a user leaves its pending email changes behind
+func optionalPragma(p string) bool {
+	return strings.HasPrefix(p, "PRAGMA foreign_keys") || strings.HasPrefix(p, "PRAGMA journal_mode")
 }
 
 func (s *sqlStore) migrate() error {
-	query := `
+	queries := []string{`
 	CREATE TABLE IF NOT EXISTS users (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		username TEXT NOT NULL UNIQUE,
 		email TEXT NOT NULL UNIQUE,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP	
-	);`
-	_, err := s.db.Exec(query)
-	return err
+	);`,
+		// audit entries are kept after the user is deleted
+		// so user_id is not a foreign key
+		`
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		action TEXT NOT NULL,
+		user_id INTEGER NOT NULL,
+		snapshot TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_user_id ON audit_log (user_id);`,
+		// pending email changes waiting for confirmation
+		// only a hash of the token is stored
+		`
+	CREATE TABLE IF NOT EXISTS email_changes (
+		token_hash TEXT PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		new_email TEXT NOT NULL,
+		expires_at DATETIME NOT NULL
+	);`,
+		// versions of the migrations in migrations.go that were applied
+		`
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+	}
+	for _, q := range queries {
+		if _, err := s.db.Exec(q); err != nil {
+			return err
+		}
+	}
+	return s.applyMigrations()
+}
+
+// now reads the configured clock
+func (s *sqlStore) now() time.Time {
+	if s.cfg.clock != nil {
+		return s.cfg.clock()
+	}
+	return time.Now()
+}
+
+// opContext derives the context for one operation
+// applying the default timeout when the caller did not set a deadline
+func (s *sqlStore) opContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.cfg.defaultTimeout <= 0 {
+		return ctx, func() {}
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, s.cfg.defaultTimeout)
+}
+
+// checkTables makes sure the users table exists
+func (s *sqlStore) checkTables() error {
+	var found bool
+	query := `SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'users')`
+	if err := s.db.QueryRow(query).Scan(&found); err != nil {
+		return fmt.Errorf("failed to read schema : %w", err)
+	}
+	if !found {
+		return ErrSchemaMissing
+	}
+	return nil
 }
 
+// Close closes the database, calls after the first one return nil
+// a store from BeginStore rolls its transaction back instead
 func (s *sqlStore) Close() error {
-	return s.db.Close()
+	if s.tx != nil {
+		err := s.tx.Rollback()
+		s.release()
+		if err != nil && err != sql.ErrTxDone {
+			return fmt.Errorf("failed to roll back transaction : %w", err)
+		}
+		return nil
+	}
+	var err error
+	s.closeOnce.Do(func() {
+		s.checkpointOnClose()
+		err = s.db.Close()
+	})
+	return err
+}
+
+// checkpointOnClose copies the WAL into the database file before closing
+// so tools opening the file without the -wal sidecar see every commit
+// a busy database is retried like other writes, a failure is only logged
+func (s *sqlStore) checkpointOnClose() {
+	if s.cfg.readOnly || s.cfg.journalMode != "WAL" {
+		return
+	}
+	ctx := context.Background()
+	err := s.retry(ctx, func() error {
+		return s.Checkpoint(ctx)
+	})
+	if err != nil {
+		slog.Warn("userstore: checkpoint on close failed", "err", err)
+	}
+}
+
+// DBStats returns the connection pool statistics
+// open, in use and idle connections and how often callers waited for one
+func (s *sqlStore) DBStats() sql.DBStats {
+	return s.db.Stats()
 }
 
 // CRUD 
-func (s *sqlStore) Create(ctx context.Context, user *User) error {
+func (s *sqlStore) Create(ctx context.Context, user *User) (err error) {
+	if s.cfg.readOnly {
+		return ErrReadOnly
+	}
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	if err := s.validate(user); err != nil {
+		return err
+	}
+	release, err := s.reserveCreate(user)
+	if err != nil {
+		return err
+	}
+	// only successful creates count against the limit
+	defer func() {
+		if err != nil {
+			release()
+		}
+	}()
 	// Using transactions to make sure it is durable
-	tx, err := s.db.BeginTx(ctx, nil)
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		return s.insertUser(ctx, tx, user)
+	})
+	if err != nil {
+		return err
+	}
+	s.created(ctx, user)
+	return nil
+}
+
+// inTx runs fn in a transaction and commits it
+// if there are some issues in fn the transaction is rolled back
+// the whole transaction is retried when the database is busy
+func (s *sqlStore) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return s.retry(ctx, func() error {
+		return s.runTx(ctx, fn)
+	})
+}
+
+// runTx is inTx without the retry, for callers that can not run fn twice
+func (s *sqlStore) runTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	if s.tx != nil {
+		return s.inSavepoint(ctx, fn)
+	}
+	tx, release, err := s.beginTx(ctx, s.cfg.txOptions)
 	if err != nil {
 		return fmt.Errorf("Failed to begin transctions : %w", err)
 	}
-	// if there are some issues in transactions
-	// it will rollback the transaction
+	defer release()
 	defer tx.Rollback()
 
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction : %w", err)
+	}
+	return nil
+}
+
+// created runs the OnCreate hooks, only call it after commit
+func (s *sqlStore) created(ctx context.Context, user *User) {
+	for _, fn := range s.cfg.onCreate {
+		fn(ctx, user)
+	}
+}
+// insertUser writes the row and its audit entry inside tx
+// and fills user.ID, the caller commits
+func (s *sqlStore) insertUser(ctx context.Context, tx *sql.Tx, user *User) error {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	var uuid sql.NullString
+	if s.cfg.idStrategy == UUID {
+		id, err := newUUID()
+		if err != nil {
+			return err
+		}
+		uuid = sql.NullString{String: id, Valid: true}
+	}
+
+	if user.Role == "" {
+		user.Role = RoleUser
+	}
+
+	// CURRENT_TIMESTAMP only has second precision
+	// the clock time keeps users created in the same second in order
+	createdAt := s.now().UTC()
+	email, err := s.storedEmail(user.Email)
+	if err != nil {
+		return err
+	}
+	metadata, err := encodeMetadata(user.Metadata)
+	if err != nil {
+		return err
+	}
+
 	// using ? to prevent sql injection from user.
-	query := `INSERT INTO users (username, email) VALUES (?, ?)`
-	result, err := tx.ExecContext(ctx, query, user.Username, user.Email)
+	query := `INSERT INTO users (username, email, created_at, created_by, uuid, role, canonical_email, email_hash, email_lower, metadata, display_name, tenant_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := tx.ExecContext(ctx, query, user.Username, email, createdAt, user.CreatedBy, uuid, user.Role, s.canonical(user.Email), s.emailHash(user.Email), s.emailLower(user.Email), metadata, displayName(user.DisplayName), s.cfg.tenant)
 	if err != nil {
-		if strings.Contains(err.Error(), "UNIQUE constraint failed"){
+		if isUniqueViolation(err) {
 			return ErrDuplicateUser
 		}
+		if isForeignKeyViolation(err) {
+			return ErrCreatorNotFound
+		}
 		return fmt.Errorf("failed to insert user: %w", err)
 	}
 	// find last id to fill the user struct
@@ -91,22 +497,24 @@ func (s *sqlStore) Create(ctx context.Context, user *User) error {
 		return fmt.Errorf("failed to get the last insert id : %w", err)
 	}
 	user.ID = id
+	user.UUID = uuid.String
+	user.CreatedAt = createdAt
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction : %w", err)
+	stored, err := s.readUserTx(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.writeAudit(ctx, tx, AuditActionCreate, stored); err != nil {
+		return err
 	}
 	return nil
 }
 func (s *sqlStore) GetById(ctx context.Context, id int64) (*User, error) {
-	var user User
-	query := `SELECT id, username, email, created_at FROM users WHERE id = ?`
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = ? AND tenant_id = ? AND deleted_at IS NULL`
 	
-	err := s.db.QueryRowContext(ctx, query, id).Scan(
-		&user.ID,
-		&user.Username,
-		&user.Email,
-		&user.CreatedAt,
-	)
+	user, err := s.scan(s.conn().QueryRowContext(ctx, query, id, s.cfg.tenant))
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -116,19 +524,263 @@ func (s *sqlStore) GetById(ctx context.Context, id int64) (*User, error) {
 	}
 	return &user, nil
 }
+// GetByUUID loads a user by the public uuid of the UUID id strategy
+func (s *sqlStore) GetByUUID(ctx context.Context, uuid string) (*User, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	query := `SELECT ` + userColumns + ` FROM users WHERE uuid = ? AND tenant_id = ? AND deleted_at IS NULL`
+	user, err := s.scan(s.conn().QueryRowContext(ctx, query, uuid, s.cfg.tenant))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("Failed to get user: %w", err)
+	}
+	return &user, nil
+}
+
+// Latest returns the most recently created user
+// id breaks ties between users created at the same time
+func (s *sqlStore) Latest(ctx context.Context) (*User, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	query := `SELECT ` + userColumns + ` FROM users WHERE tenant_id = ? AND deleted_at IS NULL
+	ORDER BY created_at DESC, id DESC LIMIT 1`
+	user, err := s.scan(s.conn().QueryRowContext(ctx, query, s.cfg.tenant))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("Failed to get user: %w", err)
+	}
+	return &user, nil
+}
+
+// GetMany loads several users with one IN query
+// ids that do not exist are left out of the map
+func (s *sqlStore) GetMany(ctx context.Context, ids []int64) (map[int64]*User, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	users := make(map[int64]*User, len(ids))
+	if len(ids) == 0 {
+		return users, nil
+	}
+
+	args := []any{s.cfg.tenant}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	query := `SELECT ` + userColumns + ` FROM users WHERE tenant_id = ? AND deleted_at IS NULL AND id IN (` + placeholders(len(ids)) + `)`
+	rows, err := s.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users : %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		u, err := s.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user : %w", err)
+		}
+		users[u.ID] = &u
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration : %w", err)
+	}
+	return users, nil
+}
+
+// GetInOrder is GetMany as a slice aligned with ids
+// ids that do not exist are nil, a repeated id gets the same user
+func (s *sqlStore) GetInOrder(ctx context.Context, ids []int64) ([]*User, error) {
+	found, err := s.GetMany(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	users := make([]*User, len(ids))
+	for i, id := range ids {
+		users[i] = found[id]
+	}
+	return users, nil
+}
+
+// placeholders returns n comma separated ? for an IN clause
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// ExistsByEmail reports whether a user has this email
+// without fetching the row
+// soft deleted users still count since they keep their email
+func (s *sqlStore) ExistsByEmail(ctx context.Context, email string) (bool, error) {
+	column, value := s.emailLookup(email)
+	return s.exists(ctx, `SELECT EXISTS(SELECT 1 FROM users WHERE `+column+` = ? AND tenant_id = ?)`, value, s.cfg.tenant)
+}
+
+// IsEmailAvailable reports whether no active user has email
+// for signup forms to warn before submitting
+// it compares like Create does, ignoring case and with
+// WithEmailCanonicalization also aliases of the address
+// the answer is advisory, a concurrent create can still take the email
+// soft deleted users do not count, but their row still holds the email
+// so Create rejects it until PurgeDeleted or Delete removes the user
+func (s *sqlStore) IsEmailAvailable(ctx context.Context, email string) (bool, error) {
+	// any email can be used again WithEmailUnique(false)
+	if s.cfg.emailNotUnique {
+		return true, nil
+	}
+	column, value := s.emailLookup(email)
+	conds := column + ` = ?`
+	args := []any{value}
+	if canon := s.canonical(email); canon.Valid {
+		conds += ` OR canonical_email = ?`
+		args = append(args, canon.String)
+	}
+	query := `SELECT EXISTS(SELECT 1 FROM users WHERE (` + conds + `) AND tenant_id = ? AND deleted_at IS NULL)`
+	taken, err := s.exists(ctx, query, append(args, s.cfg.tenant)...)
+	return !taken, err
+}
+
+// ExistsByUsername reports whether a user has this username
+func (s *sqlStore) ExistsByUsername(ctx context.Context, username string) (bool, error) {
+	return s.exists(ctx, `SELECT EXISTS(SELECT 1 FROM users WHERE username = ? AND tenant_id = ?)`, username, s.cfg.tenant)
+}
+
+// ExistingEmails reports for each of emails whether a user has it
+// with one IN query, emails are compared trimmed and ignoring case
+// the map is keyed by the emails as given
+func (s *sqlStore) ExistingEmails(ctx context.Context, emails []string) (map[string]bool, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	existing := make(map[string]bool, len(emails))
+	if len(emails) == 0 {
+		return existing, nil
+	}
+
+	column := `email_lower`
+	key := lowerEmail
+	if s.crypt != nil {
+		column = `email_hash`
+		key = s.crypt.hash
+	}
+	args := []any{s.cfg.tenant}
+	for _, e := range emails {
+		args = append(args, key(e))
+	}
+	query := `SELECT ` + column + ` FROM users WHERE tenant_id = ? AND ` + column + ` IN (` + placeholders(len(emails)) + `)`
+	rows, err := s.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check emails : %w", err)
+	}
+	defer rows.Close()
+
+	found := make(map[string]bool)
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			return nil, fmt.Errorf("failed to scan email : %w", err)
+		}
+		found[k] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration : %w", err)
+	}
+
+	for _, e := range emails {
+		existing[e] = found[key(e)]
+	}
+	return existing, nil
+}
+
+func (s *sqlStore) exists(ctx context.Context, query string, args ...any) (bool, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	var found bool
+	if err := s.conn().QueryRowContext(ctx, query, args...).Scan(&found); err != nil {
+		return false, fmt.Errorf("failed to check user : %w", err)
+	}
+	return found, nil
+}
 func (s *sqlStore) ListAll(ctx context.Context) ([]User, error) {
-	query := `SELECT id, username, email, created_at FROM users`
-	rows, err := s.db.QueryContext(ctx, query)
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	query := `SELECT ` + userColumns + ` FROM users WHERE tenant_id = ? AND deleted_at IS NULL`
+	rows, err := s.conn().QueryContext(ctx, query, s.cfg.tenant)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list users : %w", err)
 	}
 	// close rows to free database connection
 	defer rows.Close()
 
+	var users []User
+	for n := 1; rows.Next(); n++ {
+		// a cancelled context stops the scan instead of reading every row
+		if n%ctxCheckRows == 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		u, err := s.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user : %w", err)
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		// the driver interrupts the query on cancel, report why
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("error during rows iteration : %w", err)
+	}
+	return users, nil
+}
+
+// ctxCheckRows is how many rows ListAll and Iterate read between context checks
+const ctxCheckRows = 256
+
+// ListNames returns only the id and username of every user ordered by id
+// for places like dropdowns that do not need the full row
+func (s *sqlStore) ListNames(ctx context.Context) ([]UserSummary, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	query := `SELECT id, username FROM users WHERE tenant_id = ? AND deleted_at IS NULL ORDER BY id`
+	rows, err := s.conn().QueryContext(ctx, query, s.cfg.tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user names : %w", err)
+	}
+	defer rows.Close()
+
+	var names []UserSummary
+	for rows.Next() {
+		var n UserSummary
+		if err := rows.Scan(&n.ID, &n.Username); err != nil {
+			return nil, fmt.Errorf("failed to scan user name : %w", err)
+		}
+		names = append(names, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration : %w", err)
+	}
+	return names, nil
+}
+
+// ListCreatedBy returns the users created by creatorID ordered by id
+func (s *sqlStore) ListCreatedBy(ctx context.Context, creatorID int64) ([]User, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	query := `SELECT ` + userColumns + ` FROM users WHERE created_by = ? AND tenant_id = ? AND deleted_at IS NULL ORDER BY id`
+	rows, err := s.conn().QueryContext(ctx, query, creatorID, s.cfg.tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users : %w", err)
+	}
+	defer rows.Close()
+
 	var users []User
 	for rows.Next() {
-		var u User
-		if err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.CreatedAt); err != nil {
+		u, err := s.scan(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan user : %w", err)
 		}
 		users = append(users, u)
@@ -139,11 +791,81 @@ func (s *sqlStore) ListAll(ctx context.Context) ([]User, error) {
 	}
 	return users, nil
 }
-func (s *sqlStore) Update(ctx context.Context, user *User) error {
-	query := `UPDATE users SET username = ?, email = ? WHERE id = ?`
-	result, err := s.db.ExecContext(ctx, query, user.Username, user.Email, user.ID)
+
+// Iterate calls fn for every user one row at a time
+// so large tables are processed with constant memory
+// an error from fn stops the iteration and is returned as is
+func (s *sqlStore) Iterate(ctx context.Context, fn func(User) error) error {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	query := `SELECT ` + userColumns + ` FROM users WHERE tenant_id = ? AND deleted_at IS NULL ORDER BY id`
+	rows, err := s.conn().QueryContext(ctx, query, s.cfg.tenant)
 	if err != nil {
-		return fmt.Errorf("failed to update user : %w", err)
+		return fmt.Errorf("failed to list users : %w", err)
+	}
+	defer rows.Close()
+
+	for n := 1; rows.Next(); n++ {
+		if n%ctxCheckRows == 0 && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		u, err := s.scan(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan user : %w", err)
+		}
+		if err := fn(u); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("error during rows iteration : %w", err)
+	}
+	return nil
+}
+
+// Truncate removes every user with their audit trail, pending email changes, username history and tombstones
+// and resets the id sequences so the next user gets id 1, or the WithIDOffset start
+// it is meant for resetting state between tests
+func (s *sqlStore) Truncate(ctx context.Context) error {
+	if s.cfg.readOnly {
+		return ErrReadOnly
+	}
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		queries := []string{
+			`DELETE FROM email_changes`,
+			`DELETE FROM username_history`,
+			`DELETE FROM audit_log`,
+			`DELETE FROM deletions`,
+			`DELETE FROM users`,
+			`DELETE FROM sqlite_sequence WHERE name IN ('users', 'audit_log', 'deletions')`,
+		}
+		for _, q := range queries {
+			if _, err := tx.ExecContext(ctx, q); err != nil {
+				return fmt.Errorf("failed to truncate : %w", err)
+			}
+		}
+		return s.applyIDOffset(ctx, tx)
+	})
+}
+
+// RecordLogin stamps the user's last_login_at with the current time
+// call it after the caller has authenticated the user
+func (s *sqlStore) RecordLogin(ctx context.Context, id int64) error {
+	if s.cfg.readOnly {
+		return ErrReadOnly
+	}
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	query := `UPDATE users SET last_login_at = ? WHERE id = ? AND tenant_id = ? AND deleted_at IS NULL`
+	result, err := s.conn().ExecContext(ctx, query, s.now(), id, s.cfg.tenant)
+	if err != nil {
+		return fmt.Errorf("failed to record login : %w", err)
 	}
 
 	count, err := result.RowsAffected()
@@ -155,20 +877,417 @@ func (s *sqlStore) Update(ctx context.Context, user *User) error {
 	}
 	return nil
 }
-func (s *sqlStore) Delete(ctx context.Context, id int64) error {
-	query := `DELETE FROM users WHERE id = ?`
-	result, err := s.db.ExecContext(ctx, query, id)
+
+// ListRecentlyUpdated returns up to limit users, most recently changed first
+// users never updated count from their created_at
+func (s *sqlStore) ListRecentlyUpdated(ctx context.Context, limit int) ([]User, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	if limit < 0 {
+		return nil, ErrInvalidPagination
+	}
+	limit, err := s.pageLimit(limit)
 	if err != nil {
-		return fmt.Errorf("failed to delete user : %w", err)
+		return nil, err
 	}
+	query := `SELECT ` + userColumns + ` FROM users WHERE tenant_id = ? AND deleted_at IS NULL
+	ORDER BY COALESCE(updated_at, created_at) DESC, id DESC LIMIT ?`
+	rows, err := s.conn().QueryContext(ctx, query, s.cfg.tenant, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users : %w", err)
+	}
+	defer rows.Close()
 
-	count, err := result.RowsAffected()
+	var users []User
+	for rows.Next() {
+		u, err := s.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user : %w", err)
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration : %w", err)
+	}
+	return users, nil
+}
+
+// ChangesSince returns up to limit users created, updated or soft deleted
+// after since, oldest change first, for clients that sync by polling
+// soft deleted users are included with DeletedAt set so they can be removed
+// a change is the latest of created_at, updated_at and deleted_at
+func (s *sqlStore) ChangesSince(ctx context.Context, since time.Time, limit int) ([]User, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	if limit < 0 {
+		return nil, ErrInvalidPagination
+	}
+	limit, err := s.pageLimit(limit)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if count == 0 {
-		return ErrUserNotFound
+	query := `SELECT ` + userColumns + ` FROM (
+		SELECT *, MAX(COALESCE(created_at, ''), COALESCE(updated_at, ''), COALESCE(deleted_at, '')) AS changed_at
+		FROM users WHERE tenant_id = ?
+	) WHERE changed_at > ? ORDER BY changed_at, id LIMIT ?`
+	rows, err := s.conn().QueryContext(ctx, query, s.cfg.tenant, since.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list changes : %w", err)
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		u, err := s.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user : %w", err)
+		}
+		users = append(users, u)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration : %w", err)
+	}
+	return users, nil
+}
+
+// ListInactive returns users that never logged in and were created before createdBefore
+// ordered by id, for cleanup campaigns
+func (s *sqlStore) ListInactive(ctx context.Context, createdBefore time.Time) ([]User, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	query := `SELECT ` + userColumns + ` FROM users
+	WHERE last_login_at IS NULL AND created_at < ? AND tenant_id = ? AND deleted_at IS NULL ORDER BY id`
+	rows, err := s.conn().QueryContext(ctx, query, createdBefore.UTC(), s.cfg.tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list inactive users : %w", err)
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		u, err := s.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user : %w", err)
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration : %w", err)
+	}
+	return users, nil
+}
+
+// pageLimit applies the max page size to a requested limit
+func (s *sqlStore) pageLimit(limit int) (int, error) {
+	if limit <= s.cfg.maxPageSize {
+		return limit, nil
+	}
+	if s.cfg.rejectLargePages {
+		return 0, ErrLimitTooLarge
+	}
+	return s.cfg.maxPageSize, nil
+}
+
+// List returns one page of users ordered by id
+// the limit is capped by the max page size, see WithMaxPageSize
+func (s *sqlStore) List(ctx context.Context, limit, offset int) ([]User, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	if limit < 0 || offset < 0 {
+		return nil, ErrInvalidPagination
+	}
+	limit, err := s.pageLimit(limit)
+	if err != nil {
+		return nil, err
+	}
+	query := `SELECT ` + userColumns + ` FROM users WHERE tenant_id = ? AND deleted_at IS NULL ORDER BY id LIMIT ? OFFSET ?`
+	rows, err := s.conn().QueryContext(ctx, query, s.cfg.tenant, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users : %w", err)
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		u, err := s.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user : %w", err)
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration : %w", err)
+	}
+	return users, nil
+}
+
+// Page returns one page of users ordered by id with the total number of users
+// in one round trip, the total comes from a window function over the same rows
+func (s *sqlStore) Page(ctx context.Context, limit, offset int) (PageResult, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	if limit < 0 || offset < 0 {
+		return PageResult{}, ErrInvalidPagination
+	}
+	// no limit means one full page, as in Query
+	if limit == 0 {
+		limit = s.cfg.maxPageSize
+	}
+	limit, err := s.pageLimit(limit)
+	if err != nil {
+		return PageResult{}, err
+	}
+	query := `SELECT ` + userColumns + `, COUNT(*) OVER () FROM users
+	WHERE tenant_id = ? AND deleted_at IS NULL ORDER BY id LIMIT ? OFFSET ?`
+	rows, err := s.conn().QueryContext(ctx, query, s.cfg.tenant, limit, offset)
+	if err != nil {
+		return PageResult{}, fmt.Errorf("failed to list users : %w", err)
+	}
+	defer rows.Close()
+
+	var page PageResult
+	for rows.Next() {
+		u, err := s.scan(totalScanner{rows: rows, total: &page.Total})
+		if err != nil {
+			return PageResult{}, fmt.Errorf("failed to scan user : %w", err)
+		}
+		page.Users = append(page.Users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return PageResult{}, fmt.Errorf("error during rows iteration : %w", err)
+	}
+	rows.Close()
+
+	// an empty page has no row to carry the total
+	if len(page.Users) == 0 {
+		if page.Total, err = s.Count(ctx); err != nil {
+			return PageResult{}, err
+		}
+	}
+	page.HasNext = int64(offset+len(page.Users)) < page.Total
+	return page, nil
+}
+
+// totalScanner reads the window count that follows the user columns
+type totalScanner struct {
+	rows  *sql.Rows
+	total *int64
+}
+
+func (t totalScanner) Scan(dest ...any) error {
+	return t.rows.Scan(append(dest, t.total)...)
+}
+
+// Count returns the total number of users that are not soft deleted
+func (s *sqlStore) Count(ctx context.Context) (int64, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	var n int64
+	query := `SELECT COUNT(*) FROM users WHERE tenant_id = ? AND deleted_at IS NULL`
+	if err := s.conn().QueryRowContext(ctx, query, s.cfg.tenant).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count users : %w", err)
+	}
+	return n, nil
+}
+
+// CountByDomain returns how many users each email domain has
+// domains are lowercased and emails without @ are grouped under ""
+func (s *sqlStore) CountByDomain(ctx context.Context) (map[string]int64, error) {
+	if s.crypt != nil {
+		return s.countByDomainDecrypted(ctx)
+	}
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	query := `SELECT CASE WHEN instr(email, '@') > 0
+		THEN lower(substr(email, instr(email, '@') + 1)) ELSE '' END AS domain, COUNT(*)
+		FROM users WHERE tenant_id = ? AND deleted_at IS NULL GROUP BY domain`
+	rows, err := s.conn().QueryContext(ctx, query, s.cfg.tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count users by domain : %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var domain string
+		var n int64
+		if err := rows.Scan(&domain, &n); err != nil {
+			return nil, fmt.Errorf("failed to scan domain count : %w", err)
+		}
+		counts[domain] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count users by domain : %w", err)
+	}
+	return counts, nil
+}
+
+// Update writes the username, email, display name and metadata of user
+// it is a no-op when they equal the stored values
+func (s *sqlStore) Update(ctx context.Context, user *User) error {
+	_, err := s.update(ctx, user)
+	return err
+}
+
+// UpdateWithDiff is Update that also returns the json names of the fields it changed
+// like ["email"], email_verified is listed when a new email reset it
+// nil means the stored values already matched and nothing was written
+func (s *sqlStore) UpdateWithDiff(ctx context.Context, user *User) ([]string, error) {
+	return s.update(ctx, user)
+}
+
+func (s *sqlStore) update(ctx context.Context, user *User) ([]string, error) {
+	if s.cfg.readOnly {
+		return nil, ErrReadOnly
+	}
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	if err := s.validate(user); err != nil {
+		return nil, err
+	}
+	email, err := s.storedEmail(user.Email)
+	if err != nil {
+		return nil, err
+	}
+	hash := s.emailHash(user.Email)
+	lower := s.emailLower(user.Email)
+	metadata, err := encodeMetadata(user.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	var changed []string
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		// a retried attempt starts over
+		changed = nil
+		// nothing to write when the values are the ones stored
+		// so updated_at and the audit log only record real changes
+		current, err := s.readUserTx(ctx, tx, user.ID)
+		if err != nil {
+			return err
+		}
+		// a soft-deleted user is hidden like in GetById
+		if current.DeletedAt != nil {
+			return ErrUserNotFound
+		}
+		currentMetadata, err := encodeMetadata(current.Metadata)
+		if err != nil {
+			return err
+		}
+		if current.Username == user.Username && current.Email == user.Email && currentMetadata == metadata &&
+			displayName(current.DisplayName) == displayName(user.DisplayName) {
+			return nil
+		}
+
+		// a new email has not been verified yet, a new case of the same one has
+		// encrypted emails never compare equal so the hash is compared too
+		query := `UPDATE users SET username = ?, email = ?, canonical_email = ?, email_hash = ?, metadata = ?, display_name = ?, updated_at = ?,
+		email_verified = CASE WHEN email_lower = ? OR email_hash = ? THEN email_verified ELSE 0 END, email_lower = ?
+		WHERE id = ? AND tenant_id = ? AND deleted_at IS NULL`
+		result, err := tx.ExecContext(ctx, query, user.Username, email, s.canonical(user.Email), hash, metadata, displayName(user.DisplayName), s.now().UTC(),
+			lower, hash, lower, user.ID, s.cfg.tenant)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrDuplicateUser
+			}
+			return fmt.Errorf("failed to update user : %w", err)
+		}
+
+		count, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrUserNotFound
+		}
+
+		stored, err := s.readUserTx(ctx, tx, user.ID)
+		if err != nil {
+			return err
+		}
+		changed = changedFields(current, stored)
+		return s.writeAudit(ctx, tx, AuditActionUpdate, stored)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+// changedFields lists the json names of the fields Update can change
+// that differ between before and after
+func changedFields(before, after *User) []string {
+	var changed []string
+	if before.Username != after.Username {
+		changed = append(changed, "username")
+	}
+	if before.Email != after.Email {
+		changed = append(changed, "email")
+	}
+	if before.EmailVerified != after.EmailVerified {
+		changed = append(changed, "email_verified")
+	}
+	if !reflect.DeepEqual(before.Metadata, after.Metadata) {
+		changed = append(changed, "metadata")
+	}
+	if displayName(before.DisplayName) != displayName(after.DisplayName) {
+		changed = append(changed, "display_name")
+	}
+	return changed
+}
+
+// displayName is the display_name value to write, NULL for nil or empty
+func displayName(name *string) sql.NullString {
+	if name == nil || *name == "" {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *name, Valid: true}
+}
+
+// tombstone records that id was hard deleted when WithDeletionTombstones is set
+func (s *sqlStore) tombstone(ctx context.Context, tx *sql.Tx, id int64) error {
+	if !s.cfg.tombstones {
+		return nil
+	}
+	query := `INSERT INTO deletions (user_id, deleted_at) VALUES (?, ?)`
+	if _, err := tx.ExecContext(ctx, query, id, s.now().UTC()); err != nil {
+		return fmt.Errorf("failed to record deletion : %w", err)
+	}
 	return nil
 }
+
+// Delete removes the user in one transaction
+// users it created keep existing with created_by set to NULL,
+// pending email changes go with it through ON DELETE CASCADE
+// and audit entries are kept as history
+func (s *sqlStore) Delete(ctx context.Context, id int64) error {
+	if s.cfg.readOnly {
+		return ErrReadOnly
+	}
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		// snapshot the row before it is gone
+		stored, err := s.readUserTx(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		// created_by has no ON DELETE action so detach children first
+		query := `UPDATE users SET created_by = NULL WHERE created_by = ?`
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			return fmt.Errorf("failed to detach created users : %w", err)
+		}
+		query = `DELETE FROM users WHERE id = ?`
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			return fmt.Errorf("failed to delete user : %w", err)
+		}
+		if err := s.tombstone(ctx, tx, id); err != nil {
+			return err
+		}
+		return s.writeAudit(ctx, tx, AuditActionDelete, stored)
+	})
+}
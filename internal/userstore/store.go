@@ -1,14 +1,80 @@
 package userstore
 
-import "context"
+import (
+	"context"
+	"database/sql"
+	"io"
+	"time"
+)
 
 // This interface is a contract that
 // represent how crud implemented in this module
 type Store interface {
 	Create(ctx context.Context, user *User) error
+	BatchCreatePartial(ctx context.Context, users []*User) ([]CreateResult, error)
 	GetById(ctx context.Context, id int64) (*User, error)
+	GetByUUID(ctx context.Context, uuid string) (*User, error)
+	Latest(ctx context.Context) (*User, error)
+	GetByIdIncludingDeleted(ctx context.Context, id int64) (*User, error)
+	GetMany(ctx context.Context, ids []int64) (map[int64]*User, error)
+	GetInOrder(ctx context.Context, ids []int64) ([]*User, error)
+	ExistsByEmail(ctx context.Context, email string) (bool, error)
+	IsEmailAvailable(ctx context.Context, email string) (bool, error)
+	ExistingEmails(ctx context.Context, emails []string) (map[string]bool, error)
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	GetOrCreateByEmail(ctx context.Context, user *User) (created bool, err error)
+	ExistsByUsername(ctx context.Context, username string) (bool, error)
 	ListAll(ctx context.Context)([]User, error)
+	ListNames(ctx context.Context) ([]UserSummary, error)
+	ListCreatedBy(ctx context.Context, creatorID int64) ([]User, error)
+	ListDeleted(ctx context.Context) ([]User, error)
+	FullTextSearch(ctx context.Context, query string) ([]User, error)
+	Search(ctx context.Context, query string, limit, offset int) ([]User, int64, error)
+	Iterate(ctx context.Context, fn func(User) error) error
+	List(ctx context.Context, limit, offset int) ([]User, error)
+	ListRecentlyUpdated(ctx context.Context, limit int) ([]User, error)
+	ChangesSince(ctx context.Context, since time.Time, limit int) ([]User, error)
+	Count(ctx context.Context) (int64, error)
+	Page(ctx context.Context, limit, offset int) (PageResult, error)
+	Query(ctx context.Context, f UserFilter) ([]User, error)
+	ListByPrefix(ctx context.Context, prefix string) ([]User, error)
+	QueryCount(ctx context.Context, f UserFilter) (int64, error)
+	CountByDomain(ctx context.Context) (map[string]int64, error)
 	Update(ctx context.Context, user *User) error
+	UpdateWithDiff(ctx context.Context, user *User) ([]string, error)
+	SetMetadata(ctx context.Context, id int64, kv map[string]any) error
+	Patch(ctx context.Context, id int64, fields map[string]any) error
+	RenameUser(ctx context.Context, id int64, newUsername string) error
+	UsernameHistory(ctx context.Context, id int64) ([]string, error)
 	Delete(ctx context.Context, id int64) error
+	SoftDelete(ctx context.Context, id int64) error
+	PurgeDeleted(ctx context.Context, olderThan time.Duration) (int64, error)
+	UpdateRoleMany(ctx context.Context, ids []int64, role string) (int64, error)
+	RequestEmailChange(ctx context.Context, id int64, newEmail string) (token string, err error)
+	ConfirmEmailChange(ctx context.Context, token string) error
+	RecordLogin(ctx context.Context, id int64) error
+	ListInactive(ctx context.Context, createdBefore time.Time) ([]User, error)
+	MarkEmailVerified(ctx context.Context, id int64) error
+	ListUnverified(ctx context.Context) ([]User, error)
+	Truncate(ctx context.Context) error
+	Renumber(ctx context.Context) error
+	MergeUsers(ctx context.Context, keepID, removeID int64) error
+	ListAudit(ctx context.Context, userID int64) ([]AuditEntry, error)
+	Backup(ctx context.Context, destPath string) error
+	CloneTo(ctx context.Context, path string) (Store, error)
+	Checkpoint(ctx context.Context) error
+	Verify(ctx context.Context) error
+	ExportUser(ctx context.Context, id int64, format string, w io.Writer) error
+	CheckSchema(ctx context.Context) error
+	FindOrphans(ctx context.Context) (OrphanReport, error)
+	SchemaVersion(ctx context.Context) (int, error)
+	ExportCSV(ctx context.Context, w io.Writer) (int, error)
+	ExportJSONL(ctx context.Context, w io.Writer) error
+	DumpJSON(ctx context.Context, w io.Writer) error
+	LoadJSON(ctx context.Context, r io.Reader) error
+	ImportCSV(ctx context.Context, r io.Reader, skipDuplicates bool) (imported, skipped int, err error)
+	ValidateCSV(ctx context.Context, r io.Reader) (ValidationReport, error)
+	Config() StoreConfig
+	DBStats() sql.DBStats
 	Close() error	
 }
\ No newline at end of file
@@ -2,7 +2,11 @@ package userstore
 
 import (
 	"context"
+	"errors"
+	"maps"
+	"slices"
 	"testing"
+	"time"
 )
 
 func StoreTest(t *testing.T) Store {
@@ -19,6 +23,27 @@ func StoreTest(t *testing.T) Store {
 	return store
 }
 
+// storeWithOptions is StoreTest for stores that need options
+func storeWithOptions(t *testing.T, opts ...Option) Store {
+	t.Helper()
+	return storeAt(t, ":memory:", opts...)
+}
+
+// storeAt opens a store on a file path and closes it with the test
+func storeAt(t *testing.T, path string, opts ...Option) Store {
+	t.Helper()
+
+	store, err := NewDb(path, opts...)
+	if err != nil {
+		t.Fatalf("Create DB: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+	return store
+}
+
 // Create user test
 func TestCreateUser(t *testing.T) {
 	store := StoreTest(t)
@@ -49,6 +74,35 @@ func TestCreateDuplicateUser(t *testing.T) {
 	}
 }
 
+// created_at precision test
+func TestCreatedAtPrecision(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tick := start
+	clock := func() time.Time {
+		tick = tick.Add(time.Millisecond)
+		return tick
+	}
+	store := storeWithOptions(t, WithClock(clock))
+	ctx := context.Background()
+
+	a := &User{Username: "p1", Email: "p1@test.com"}
+	b := &User{Username: "p2", Email: "p2@test.com"}
+	_ = store.Create(ctx, a)
+	_ = store.Create(ctx, b)
+
+	gotA, _ := store.GetById(ctx, a.ID)
+	gotB, _ := store.GetById(ctx, b.ID)
+	if !gotA.CreatedAt.Equal(a.CreatedAt) || !gotB.CreatedAt.Equal(b.CreatedAt) {
+		t.Errorf("Expected stored times to keep milliseconds, got %v %v", gotA.CreatedAt, gotB.CreatedAt)
+	}
+	if !gotA.CreatedAt.Before(gotB.CreatedAt) {
+		t.Errorf("Expected ordered timestamps, got %v %v", gotA.CreatedAt, gotB.CreatedAt)
+	}
+	if gotA.CreatedAt.Truncate(time.Second) != gotB.CreatedAt.Truncate(time.Second) {
+		t.Error("Expected both users in the same second")
+	}
+}
+
 // Get by id test
 func TestGetByID(t *testing.T) {
 	store := StoreTest(t)
@@ -66,6 +120,29 @@ func TestGetByID(t *testing.T) {
 	}
 }
 
+// Latest user test
+func TestLatest(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	if _, err := store.Latest(ctx); err != ErrUserNotFound {
+		t.Fatalf("Expected user not found on empty table, got %v", err)
+	}
+
+	var last *User
+	for _, name := range []string{"l1", "l2", "l3"} {
+		last = &User{Username: name, Email: name + "@test.com"}
+		_ = store.Create(ctx, last)
+	}
+	got, err := store.Latest(ctx)
+	if err != nil {
+		t.Fatalf("Latest failed : %v", err)
+	}
+	if got.ID != last.ID || got.Username != "l3" {
+		t.Errorf("Expected the last created user, got %+v", got)
+	}
+}
+
 // user not found test
 func TestGetUserNotFound(t *testing.T) {
 	store := StoreTest(t)
@@ -95,6 +172,42 @@ func TestListAllUsers(t *testing.T) {
 	}
 }
 
+// List all cancellation test
+func TestListAllCancelled(t *testing.T) {
+	store := StoreTest(t)
+
+	// more rows than one context check covers
+	seed := `WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < ?)
+	INSERT INTO users (username, email) SELECT 'u' || i, 'u' || i || '@test.com' FROM n`
+	if _, err := store.(*sqlStore).db.Exec(seed, 4*ctxCheckRows); err != nil {
+		t.Fatalf("Seed failed : %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := store.ListAll(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context canceled, got %v", err)
+	}
+
+	// cancelled mid scan the loop stops by the next context check
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	seen := 0
+	err := store.Iterate(ctx, func(User) error {
+		seen++
+		if seen == 10 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context canceled, got %v", err)
+	}
+	if seen > 10+ctxCheckRows {
+		t.Errorf("Expected the scan to stop within %d rows of the cancel, read %d", ctxCheckRows, seen)
+	}
+}
+
 // update test
 func TestUpdateUser(t *testing.T) {
 	store := StoreTest(t)
@@ -125,6 +238,44 @@ func TestUpdateNonExistUser(t *testing.T) {
 	}
 }
 
+// Update to a taken email test
+func TestUpdateDuplicateEmail(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	_ = store.Create(ctx, &User{Username: "first", Email: "first@test.com"})
+	second := &User{Username: "second", Email: "second@test.com"}
+	_ = store.Create(ctx, second)
+
+	second.Email = "first@test.com"
+	if err := store.Update(ctx, second); err != ErrDuplicateUser {
+		t.Fatalf("Expected duplicate user, got %v", err)
+	}
+}
+
+// Update diff test
+func TestUpdateWithDiff(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	u := &User{Username: "differ", Email: "old@test.com"}
+	_ = store.Create(ctx, u)
+
+	u.Email = "new@test.com"
+	changed, err := store.UpdateWithDiff(ctx, u)
+	if err != nil {
+		t.Fatalf("UpdateWithDiff failed : %v", err)
+	}
+	if !slices.Equal(changed, []string{"email"}) {
+		t.Errorf("Expected [email] changed, got %v", changed)
+	}
+
+	changed, err = store.UpdateWithDiff(ctx, u)
+	if err != nil || changed != nil {
+		t.Errorf("Expected no changes, got %v %v", changed, err)
+	}
+}
+
 // Delete test
 func TestDeleteUser(t *testing.T) {
 	store := StoreTest(t)
@@ -147,6 +298,44 @@ func TestDeleteUser(t *testing.T) {
 	}
 }
 
+// Delete user with dependents test
+func TestDeleteCascade(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	parent := &User{Username: "parent", Email: "parent@test.com"}
+	_ = store.Create(ctx, parent)
+	child := &User{Username: "child", Email: "child@test.com", CreatedBy: &parent.ID}
+	if err := store.Create(ctx, child); err != nil {
+		t.Fatalf("Create child failed : %v", err)
+	}
+	if _, err := store.RequestEmailChange(ctx, parent.ID, "new@test.com"); err != nil {
+		t.Fatalf("RequestEmailChange failed : %v", err)
+	}
+
+	if err := store.Delete(ctx, parent.ID); err != nil {
+		t.Fatalf("Delete failed : %v", err)
+	}
+
+	got, err := store.GetById(ctx, child.ID)
+	if err != nil {
+		t.Fatalf("GetById child failed : %v", err)
+	}
+	if got.CreatedBy != nil {
+		t.Errorf("Expected created_by to be NULL, got %d", *got.CreatedBy)
+	}
+	entries, err := store.ListAudit(ctx, parent.ID)
+	if err != nil || len(entries) != 2 {
+		t.Errorf("Expected create and delete audit entries, got %d %v", len(entries), err)
+	}
+	var pending int
+	db := store.(*sqlStore).db
+	_ = db.QueryRow(`SELECT COUNT(*) FROM email_changes WHERE user_id = ?`, parent.ID).Scan(&pending)
+	if pending != 0 {
+		t.Errorf("Expected email changes to be removed, got %d", pending)
+	}
+}
+
 func TestDeleteNonExistUser(t *testing.T) {
 	store := StoreTest(t)
 	ctx := context.Background()
@@ -165,6 +354,17 @@ func TestStoreClose(t *testing.T) {
 	}
 }
 
+// Close twice test
+func TestStoreCloseTwice(t *testing.T) {
+	store := StoreTest(t)
+	if err := store.Close(); err != nil {
+		t.Errorf("Failed to close store : %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Errorf("Expected second close to return nil, got %v", err)
+	}
+}
+
 // Empty list test
 func TestListEmpty(t *testing.T) {
 	store := StoreTest(t)
@@ -231,3 +431,743 @@ func TestOperationsOnClosedDB(t *testing.T){
 	}
 
 }
+
+// List inactive users test
+func TestListInactive(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := storeWithOptions(t, WithClock(clock.Now))
+	ctx := context.Background()
+
+	old := &User{Username: "old", Email: "old@test.com"}
+	active := &User{Username: "active", Email: "active@test.com"}
+	_ = store.Create(ctx, old)
+	_ = store.Create(ctx, active)
+	_ = store.RecordLogin(ctx, active.ID)
+
+	clock.now = clock.now.AddDate(0, 6, 0)
+	_ = store.Create(ctx, &User{Username: "recent", Email: "recent@test.com"})
+
+	users, err := store.ListInactive(ctx, clock.now.AddDate(0, -1, 0))
+	if err != nil {
+		t.Fatalf("ListInactive failed : %v", err)
+	}
+	if len(users) != 1 || users[0].ID != old.ID {
+		t.Errorf("Expected only the old user, got %+v", users)
+	}
+}
+
+// Recently updated listing test
+func TestListRecentlyUpdated(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := storeWithOptions(t, WithClock(clock.Now))
+	ctx := context.Background()
+
+	var users []*User
+	for _, name := range []string{"r1", "r2", "r3"} {
+		clock.now = clock.now.Add(time.Minute)
+		u := &User{Username: name, Email: name + "@test.com"}
+		_ = store.Create(ctx, u)
+		users = append(users, u)
+	}
+	clock.now = clock.now.Add(time.Minute)
+	users[0].Username = "r1-renamed"
+	if err := store.Update(ctx, users[0]); err != nil {
+		t.Fatalf("Update failed : %v", err)
+	}
+
+	got, err := store.ListRecentlyUpdated(ctx, 2)
+	if err != nil {
+		t.Fatalf("ListRecentlyUpdated failed : %v", err)
+	}
+	if len(got) != 2 || got[0].ID != users[0].ID || got[1].ID != users[2].ID {
+		t.Fatalf("Expected the updated user then the newest, got %+v", got)
+	}
+	if got[0].UpdatedAt == nil || !got[0].UpdatedAt.Equal(clock.now) {
+		t.Errorf("Expected updated_at %v, got %v", clock.now, got[0].UpdatedAt)
+	}
+	if got[1].UpdatedAt != nil {
+		t.Errorf("Expected no updated_at for a user never updated, got %v", got[1].UpdatedAt)
+	}
+}
+
+// Pagination test
+func TestListPage(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"p1", "p2", "p3"} {
+		_ = store.Create(ctx, &User{Username: name, Email: name + "@test.com"})
+	}
+
+	users, err := store.List(ctx, 2, 1)
+	if err != nil {
+		t.Fatalf("List failed : %v", err)
+	}
+	if len(users) != 2 {
+		t.Fatalf("Expected 2 users, got %d", len(users))
+	}
+	if users[0].Username != "p2" || users[1].Username != "p3" {
+		t.Errorf("Unexpected page %s, %s", users[0].Username, users[1].Username)
+	}
+
+	if _, err := store.List(ctx, -1, 0); err != ErrInvalidPagination {
+		t.Errorf("Expected invalid pagination error, got %v", err)
+	}
+}
+
+// Count test
+func TestCount(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	n, err := store.Count(ctx)
+	if err != nil {
+		t.Fatalf("Count failed : %v", err)
+	}
+	if n != 0 {
+		t.Errorf("Expected 0 users, got %d", n)
+	}
+
+	_ = store.Create(ctx, &User{Username: "c1", Email: "c1@test.com"})
+	_ = store.Create(ctx, &User{Username: "c2", Email: "c2@test.com"})
+
+	n, _ = store.Count(ctx)
+	if n != 2 {
+		t.Errorf("Expected 2 users, got %d", n)
+	}
+}
+
+// Count by domain test
+func TestCountByDomain(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+	_ = store.Create(ctx, &User{Username: "a1", Email: "a1@alpha.com"})
+	_ = store.Create(ctx, &User{Username: "a2", Email: "a2@Alpha.com"})
+	_ = store.Create(ctx, &User{Username: "b1", Email: "b1@beta.org"})
+	// malformed emails can only come from old rows so insert one directly
+	db := store.(*sqlStore).db
+	if _, err := db.Exec(`INSERT INTO users (username, email) VALUES ('bad', 'no-at-sign')`); err != nil {
+		t.Fatalf("Insert failed : %v", err)
+	}
+
+	counts, err := store.CountByDomain(ctx)
+	if err != nil {
+		t.Fatalf("CountByDomain failed : %v", err)
+	}
+	if counts["alpha.com"] != 2 || counts["beta.org"] != 1 || counts[""] != 1 {
+		t.Errorf("Unexpected counts : %v", counts)
+	}
+	if len(counts) != 3 {
+		t.Errorf("Expected 3 domains, got %d", len(counts))
+	}
+}
+
+// Iterate test
+func TestIterate(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	var want int64
+	for _, name := range []string{"i1", "i2", "i3"} {
+		u := &User{Username: name, Email: name + "@test.com"}
+		_ = store.Create(ctx, u)
+		want += u.ID
+	}
+
+	var sum int64
+	err := store.Iterate(ctx, func(u User) error {
+		sum += u.ID
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Iterate failed : %v", err)
+	}
+	if sum != want {
+		t.Errorf("Expected id sum %d, got %d", want, sum)
+	}
+}
+
+// Iterate stops on callback error test
+func TestIterateStopsOnError(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"i1", "i2", "i3", "i4"} {
+		_ = store.Create(ctx, &User{Username: name, Email: name + "@test.com"})
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err := store.Iterate(ctx, func(u User) error {
+		calls++
+		if calls == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Fatalf("Expected callback error, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected iteration to stop after 2 rows, got %d", calls)
+	}
+
+	// rows are closed so the store is still usable
+	if _, err := store.Count(ctx); err != nil {
+		t.Errorf("Count after Iterate failed : %v", err)
+	}
+}
+
+// Exists helpers test
+func TestExistsByEmailAndUsername(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	_ = store.Create(ctx, &User{Username: "here", Email: "here@test.com"})
+
+	found, err := store.ExistsByEmail(ctx, "here@test.com")
+	if err != nil || !found {
+		t.Errorf("Expected email to exist, got %v %v", found, err)
+	}
+	found, err = store.ExistsByEmail(ctx, "gone@test.com")
+	if err != nil || found {
+		t.Errorf("Expected email to be absent, got %v %v", found, err)
+	}
+
+	found, err = store.ExistsByUsername(ctx, "here")
+	if err != nil || !found {
+		t.Errorf("Expected username to exist, got %v %v", found, err)
+	}
+	found, err = store.ExistsByUsername(ctx, "gone")
+	if err != nil || found {
+		t.Errorf("Expected username to be absent, got %v %v", found, err)
+	}
+}
+
+// Email availability test
+func TestIsEmailAvailable(t *testing.T) {
+	store := storeWithOptions(t, WithEmailCanonicalization(true))
+	ctx := context.Background()
+
+	u := &User{Username: "taken", Email: "taken@gmail.com"}
+	_ = store.Create(ctx, u)
+
+	for email, want := range map[string]bool{
+		"free@test.com":      true,
+		"Taken@Gmail.com":    false,
+		"t.aken+x@gmail.com": false,
+	} {
+		got, err := store.IsEmailAvailable(ctx, email)
+		if err != nil {
+			t.Fatalf("IsEmailAvailable failed : %v", err)
+		}
+		if got != want {
+			t.Errorf("Expected %s available %v, got %v", email, want, got)
+		}
+	}
+
+	_ = store.SoftDelete(ctx, u.ID)
+	if ok, _ := store.IsEmailAvailable(ctx, "taken@gmail.com"); !ok {
+		t.Error("Expected a soft deleted user's email to be available")
+	}
+}
+
+// Email case test
+func TestEmailCase(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	u := &User{Username: "john", Email: "John.Doe@X.com"}
+	if err := store.Create(ctx, u); err != nil {
+		t.Fatalf("Create failed : %v", err)
+	}
+
+	got, err := store.GetByEmail(ctx, "john.doe@x.com")
+	if err != nil {
+		t.Fatalf("GetByEmail failed : %v", err)
+	}
+	if got.ID != u.ID || got.Email != "John.Doe@X.com" {
+		t.Errorf("Expected the display form, got %+v", got)
+	}
+
+	err = store.Create(ctx, &User{Username: "other", Email: "JOHN.DOE@x.com"})
+	if err != ErrDuplicateUser {
+		t.Errorf("Expected duplicate user, got %v", err)
+	}
+
+	// a new case of the same address keeps it verified
+	_ = store.MarkEmailVerified(ctx, u.ID)
+	got.Email = "john.doe@x.com"
+	if err := store.Update(ctx, got); err != nil {
+		t.Fatalf("Update failed : %v", err)
+	}
+	got, _ = store.GetById(ctx, u.ID)
+	if got.Email != "john.doe@x.com" || !got.EmailVerified {
+		t.Errorf("Expected verified lowercase email, got %+v", got)
+	}
+}
+
+// Bulk email existence test
+func TestExistingEmails(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	_ = store.Create(ctx, &User{Username: "one", Email: "one@test.com"})
+	_ = store.Create(ctx, &User{Username: "two", Email: "Two@Test.com"})
+
+	emails := []string{"one@test.com", " two@test.com ", "new@test.com", "ONE@TEST.COM"}
+	got, err := store.ExistingEmails(ctx, emails)
+	if err != nil {
+		t.Fatalf("ExistingEmails failed : %v", err)
+	}
+	want := map[string]bool{"one@test.com": true, " two@test.com ": true, "new@test.com": false, "ONE@TEST.COM": true}
+	if !maps.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+// Duplicate detection by error code test
+func TestUniqueViolationByCode(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+	db := store.(*sqlStore).db
+
+	_ = store.Create(ctx, &User{Username: "dup", Email: "dup@test.com"})
+	_, err := db.ExecContext(ctx, `INSERT INTO users (username, email) VALUES (?, ?)`, "dup", "other@test.com")
+	if !isUniqueViolation(err) {
+		t.Fatalf("Expected unique violation code, got %v", err)
+	}
+
+	// the message alone is not enough any more
+	if isUniqueViolation(errors.New("UNIQUE constraint failed: users.username")) {
+		t.Error("Expected plain error to not be treated as unique violation")
+	}
+
+	err = store.Create(ctx, &User{Username: "dup2", Email: "dup@test.com"})
+	if err != ErrDuplicateUser {
+		t.Errorf("Expected duplicate user error, got %v", err)
+	}
+}
+
+// Default timeout test
+func TestDefaultTimeout(t *testing.T) {
+	store := storeWithOptions(t, WithDefaultTimeout(20*time.Millisecond))
+	ctx := context.Background()
+
+	for _, name := range []string{"t1", "t2", "t3", "t4", "t5"} {
+		_ = store.Create(ctx, &User{Username: name, Email: name + "@test.com"})
+	}
+
+	// a slow consumer keeps the query running past the default timeout
+	err := store.Iterate(ctx, func(u User) error {
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got %v", err)
+	}
+}
+
+// Caller deadline is kept test
+func TestDefaultTimeoutKeepsCallerDeadline(t *testing.T) {
+	store := storeWithOptions(t, WithDefaultTimeout(time.Millisecond)).(*sqlStore)
+
+	parent, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+
+	ctx, done := store.opContext(parent)
+	defer done()
+	if ctx != parent {
+		t.Error("Expected caller context with a deadline to be used as is")
+	}
+}
+
+// Batch lookup test
+func TestGetMany(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	u1 := &User{Username: "m1", Email: "m1@test.com"}
+	u2 := &User{Username: "m2", Email: "m2@test.com"}
+	_ = store.Create(ctx, u1)
+	_ = store.Create(ctx, u2)
+
+	users, err := store.GetMany(ctx, []int64{u1.ID, 999, u2.ID})
+	if err != nil {
+		t.Fatalf("GetMany failed : %v", err)
+	}
+	if len(users) != 2 {
+		t.Fatalf("Expected 2 users, got %d", len(users))
+	}
+	if users[u1.ID].Username != "m1" || users[u2.ID].Username != "m2" {
+		t.Error("Unexpected users in result")
+	}
+	if _, ok := users[999]; ok {
+		t.Error("Expected missing id to be omitted")
+	}
+
+	empty, err := store.GetMany(ctx, nil)
+	if err != nil || len(empty) != 0 {
+		t.Errorf("Expected empty map, got %v %v", empty, err)
+	}
+}
+
+// Batch get in input order test
+func TestGetInOrder(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"o1", "o2", "o3"} {
+		_ = store.Create(ctx, &User{Username: name, Email: name + "@test.com"})
+	}
+
+	users, err := store.GetInOrder(ctx, []int64{3, 1, 99, 2})
+	if err != nil {
+		t.Fatalf("GetInOrder failed : %v", err)
+	}
+	if len(users) != 4 {
+		t.Fatalf("Expected 4 entries, got %d", len(users))
+	}
+	if users[0].Username != "o3" || users[1].Username != "o1" || users[2] != nil || users[3].Username != "o2" {
+		t.Errorf("Unexpected order %v", users)
+	}
+}
+
+// OnCreate hook test
+func TestOnCreateHook(t *testing.T) {
+	var got []*User
+	store := storeWithOptions(t, WithOnCreate(func(ctx context.Context, u *User) {
+		got = append(got, u)
+	}))
+	ctx := context.Background()
+
+	u := &User{Username: "hook", Email: "hook@test.com"}
+	if err := store.Create(ctx, u); err != nil {
+		t.Fatalf("Create failed : %v", err)
+	}
+	if len(got) != 1 || got[0].ID != u.ID || got[0].ID == 0 {
+		t.Fatalf("Expected hook to receive created user, got %v", got)
+	}
+
+	// a failed create must not fire the hook
+	_ = store.Create(ctx, &User{Username: "hook", Email: "hook@test.com"})
+	if len(got) != 1 {
+		t.Errorf("Expected hook to fire once, fired %d times", len(got))
+	}
+}
+
+// Last login test
+func TestRecordLogin(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	u := &User{Username: "l", Email: "l@test.com"}
+	_ = store.Create(ctx, u)
+
+	got, _ := store.GetById(ctx, u.ID)
+	if got.LastLoginAt != nil {
+		t.Fatalf("Expected no login yet, got %v", got.LastLoginAt)
+	}
+
+	if err := store.RecordLogin(ctx, u.ID); err != nil {
+		t.Fatalf("RecordLogin failed : %v", err)
+	}
+	got, _ = store.GetById(ctx, u.ID)
+	if got.LastLoginAt == nil || time.Since(*got.LastLoginAt) > time.Minute {
+		t.Errorf("Expected last login to be about now, got %v", got.LastLoginAt)
+	}
+
+	if err := store.RecordLogin(ctx, 999); err != ErrUserNotFound {
+		t.Errorf("Expected user not found, got %v", err)
+	}
+}
+
+// Truncate test
+func TestTruncate(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	// empty table is fine
+	if err := store.Truncate(ctx); err != nil {
+		t.Fatalf("Truncate on empty table failed : %v", err)
+	}
+
+	_ = store.Create(ctx, &User{Username: "t1", Email: "t1@test.com"})
+	_ = store.Create(ctx, &User{Username: "t2", Email: "t2@test.com"})
+
+	if err := store.Truncate(ctx); err != nil {
+		t.Fatalf("Truncate failed : %v", err)
+	}
+	if n, _ := store.Count(ctx); n != 0 {
+		t.Fatalf("Expected 0 users, got %d", n)
+	}
+
+	u := &User{Username: "t3", Email: "t3@test.com"}
+	_ = store.Create(ctx, u)
+	if u.ID != 1 {
+		t.Errorf("Expected id 1 after truncate, got %d", u.ID)
+	}
+}
+
+// Ownership test
+func TestListCreatedBy(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	admin := &User{Username: "admin", Email: "admin@test.com"}
+	_ = store.Create(ctx, admin)
+
+	child := &User{Username: "child", Email: "child@test.com", CreatedBy: &admin.ID}
+	if err := store.Create(ctx, child); err != nil {
+		t.Fatalf("Create child failed : %v", err)
+	}
+	_ = store.Create(ctx, &User{Username: "self", Email: "self@test.com"})
+
+	users, err := store.ListCreatedBy(ctx, admin.ID)
+	if err != nil {
+		t.Fatalf("ListCreatedBy failed : %v", err)
+	}
+	if len(users) != 1 || users[0].ID != child.ID {
+		t.Fatalf("Expected only the child user, got %v", users)
+	}
+	if users[0].CreatedBy == nil || *users[0].CreatedBy != admin.ID {
+		t.Errorf("Expected created_by %d, got %v", admin.ID, users[0].CreatedBy)
+	}
+}
+
+// Bad creator reference test
+func TestCreateWithUnknownCreator(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	missing := int64(999)
+	err := store.Create(ctx, &User{Username: "orphan", Email: "orphan@test.com", CreatedBy: &missing})
+	if err != ErrCreatorNotFound {
+		t.Fatalf("Expected creator not found error, got %v", err)
+	}
+}
+
+// Config introspection test
+func TestConfig(t *testing.T) {
+	store := storeWithOptions(t,
+		WithDefaultTimeout(3*time.Second),
+		WithDeniedEmailDomains("Spam.io", "junk.io"),
+		WithIDStrategy(UUID),
+	)
+
+	cfg := store.Config()
+	if cfg.Path != ":memory:" || cfg.Table != "users" {
+		t.Errorf("Unexpected path or table %q %q", cfg.Path, cfg.Table)
+	}
+	if cfg.DefaultTimeout != 3*time.Second {
+		t.Errorf("Expected default timeout 3s, got %v", cfg.DefaultTimeout)
+	}
+	if len(cfg.DeniedEmailDomains) != 2 || cfg.DeniedEmailDomains[1] != "spam.io" {
+		t.Errorf("Unexpected denied domains %v", cfg.DeniedEmailDomains)
+	}
+	if cfg.IDStrategy != UUID || cfg.ReadOnly {
+		t.Errorf("Unexpected id strategy or read-only %v %v", cfg.IDStrategy, cfg.ReadOnly)
+	}
+
+	// the copy is detached from the store
+	cfg.DeniedEmailDomains[0] = "changed"
+	if store.Config().DeniedEmailDomains[0] != "junk.io" {
+		t.Error("Expected Config to return a copy")
+	}
+}
+
+// Pool statistics test
+func TestDBStats(t *testing.T) {
+	store := StoreTest(t)
+	if _, err := store.Count(context.Background()); err != nil {
+		t.Fatalf("Count failed : %v", err)
+	}
+	if stats := store.DBStats(); stats.OpenConnections < 1 {
+		t.Errorf("Expected at least one open connection, got %d", stats.OpenConnections)
+	}
+}
+
+// Id and username list test
+func TestListNames(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	var want []UserSummary
+	for _, name := range []string{"n1", "n2", "n3"} {
+		u := &User{Username: name, Email: name + "@test.com"}
+		if err := store.Create(ctx, u); err != nil {
+			t.Fatalf("Create failed : %v", err)
+		}
+		want = append(want, UserSummary{ID: u.ID, Username: name})
+	}
+
+	got, err := store.ListNames(ctx)
+	if err != nil {
+		t.Fatalf("ListNames failed : %v", err)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+// Change feed test
+func TestChangesSince(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	store := storeWithOptions(t, WithClock(clock.Now))
+
+	var users []*User
+	for _, name := range []string{"c1", "c2", "c3", "c4"} {
+		u := &User{Username: name, Email: name + "@test.com"}
+		_ = store.Create(ctx, u)
+		users = append(users, u)
+	}
+
+	clock.now = clock.now.Add(time.Minute)
+	since := clock.now
+	clock.now = clock.now.Add(time.Minute)
+	users[2].Username = "c3-renamed"
+	if err := store.Update(ctx, users[2]); err != nil {
+		t.Fatalf("Update failed : %v", err)
+	}
+	clock.now = clock.now.Add(time.Minute)
+	if err := store.SoftDelete(ctx, users[0].ID); err != nil {
+		t.Fatalf("SoftDelete failed : %v", err)
+	}
+	clock.now = clock.now.Add(time.Minute)
+	created := &User{Username: "c5", Email: "c5@test.com"}
+	_ = store.Create(ctx, created)
+
+	got, err := store.ChangesSince(ctx, since, 10)
+	if err != nil {
+		t.Fatalf("ChangesSince failed : %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("Expected 3 changed users, got %d", len(got))
+	}
+	if got[0].ID != users[2].ID || got[1].ID != users[0].ID || got[2].ID != created.ID {
+		t.Errorf("Unexpected order %d %d %d", got[0].ID, got[1].ID, got[2].ID)
+	}
+	if got[1].DeletedAt == nil {
+		t.Error("Expected the soft deleted user to have DeletedAt set")
+	}
+
+	if page, _ := store.ChangesSince(ctx, since, 1); len(page) != 1 || page[0].ID != users[2].ID {
+		t.Errorf("Expected limit to keep the oldest change, got %v", page)
+	}
+}
+
+// Max page size test
+func TestMaxPageSize(t *testing.T) {
+	ctx := context.Background()
+	store := storeWithOptions(t, WithMaxPageSize(2))
+	for _, name := range []string{"m1", "m2", "m3"} {
+		_ = store.Create(ctx, &User{Username: name, Email: name + "@test.com"})
+	}
+
+	users, err := store.List(ctx, 100000, 0)
+	if err != nil {
+		t.Fatalf("List failed : %v", err)
+	}
+	if len(users) != 2 {
+		t.Errorf("Expected the page capped at 2, got %d", len(users))
+	}
+	if users, _ := store.Query(ctx, UserFilter{}); len(users) != 2 {
+		t.Errorf("Expected Query capped at 2, got %d", len(users))
+	}
+
+	strict := storeWithOptions(t, WithMaxPageSize(2), WithRejectLargePages(true))
+	if _, err := strict.List(ctx, 100000, 0); err != ErrLimitTooLarge {
+		t.Errorf("Expected ErrLimitTooLarge, got %v", err)
+	}
+	if _, err := strict.List(ctx, 2, 0); err != nil {
+		t.Errorf("Expected a limit at the max to pass, got %v", err)
+	}
+
+	if got := StoreTest(t).Config().MaxPageSize; got != defaultMaxPageSize {
+		t.Errorf("Expected default max page size %d, got %d", defaultMaxPageSize, got)
+	}
+}
+
+// Page with total test
+func TestPage(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+	for _, name := range []string{"pg1", "pg2", "pg3"} {
+		_ = store.Create(ctx, &User{Username: name, Email: name + "@test.com"})
+	}
+
+	first, err := store.Page(ctx, 2, 0)
+	if err != nil {
+		t.Fatalf("Page failed : %v", err)
+	}
+	if len(first.Users) != 2 || first.Total != 3 || !first.HasNext {
+		t.Errorf("Unexpected first page %d users, total %d, has next %v", len(first.Users), first.Total, first.HasNext)
+	}
+
+	last, err := store.Page(ctx, 2, 2)
+	if err != nil {
+		t.Fatalf("Page failed : %v", err)
+	}
+	if len(last.Users) != 1 || last.Users[0].Username != "pg3" || last.Total != 3 || last.HasNext {
+		t.Errorf("Unexpected last page %d users, total %d, has next %v", len(last.Users), last.Total, last.HasNext)
+	}
+
+	past, _ := store.Page(ctx, 2, 10)
+	if len(past.Users) != 0 || past.Total != 3 || past.HasNext {
+		t.Errorf("Unexpected page past the end %+v", past)
+	}
+
+	// no limit is one full page as in Query
+	all, err := store.Page(ctx, 0, 0)
+	if err != nil {
+		t.Fatalf("Page without limit failed : %v", err)
+	}
+	if len(all.Users) != 3 || all.Total != 3 || all.HasNext {
+		t.Errorf("Unexpected page without limit %d users, total %d, has next %v", len(all.Users), all.Total, all.HasNext)
+	}
+}
+
+// Display name test
+func TestDisplayName(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	name := "Ada Lovelace"
+	u := &User{Username: "ada", Email: "ada@test.com", DisplayName: &name}
+	if err := store.Create(ctx, u); err != nil {
+		t.Fatalf("Create failed : %v", err)
+	}
+	got, err := store.GetById(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("GetById failed : %v", err)
+	}
+	if got.DisplayName == nil || *got.DisplayName != name || got.Name() != name {
+		t.Errorf("Expected display name %q, got %v", name, got.DisplayName)
+	}
+
+	// a display name is not unique
+	twin := &User{Username: "ada2", Email: "ada2@test.com", DisplayName: &name}
+	if err := store.Create(ctx, twin); err != nil {
+		t.Fatalf("Create with the same display name failed : %v", err)
+	}
+
+	renamed := "Countess of Lovelace"
+	got.DisplayName = &renamed
+	changed, err := store.UpdateWithDiff(ctx, got)
+	if err != nil {
+		t.Fatalf("UpdateWithDiff failed : %v", err)
+	}
+	if len(changed) != 1 || changed[0] != "display_name" {
+		t.Errorf("Expected display_name changed, got %v", changed)
+	}
+
+	// clearing it falls back to the username
+	if err := store.Patch(ctx, u.ID, map[string]any{"display_name": ""}); err != nil {
+		t.Fatalf("Patch failed : %v", err)
+	}
+	got, _ = store.GetById(ctx, u.ID)
+	if got.DisplayName != nil || got.Name() != "ada" {
+		t.Errorf("Expected no display name and the username as name, got %v %q", got.DisplayName, got.Name())
+	}
+}
@@ -0,0 +1,45 @@
+// Package storetest has helpers for tests of code that uses a userstore.Store
+package storetest
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/dotenv213/umm/internal/userstore"
+)
+
+// dsn is an in memory database shared by every connection of the pool
+const dsn = "file:umm_storetest?mode=memory&cache=shared"
+
+var (
+	once    sync.Once
+	base    userstore.Store
+	baseErr error
+)
+
+// TxTestStore returns a store whose changes are rolled back when the test ends
+// so tests do not see each other's data
+// the schema is created once per test binary and every test runs in
+// its own transaction on top of it, which keeps setup fast
+// sqlite allows one writer, tests using it must not call t.Parallel
+func TxTestStore(t testing.TB) userstore.Store {
+	t.Helper()
+	once.Do(func() {
+		base, baseErr = userstore.NewDb(dsn)
+	})
+	if baseErr != nil {
+		t.Fatalf("failed to open test database : %v", baseErr)
+	}
+
+	store, err := userstore.BeginStore(context.Background(), base)
+	if err != nil {
+		t.Fatalf("failed to begin test transaction : %v", err)
+	}
+	t.Cleanup(func() {
+		if err := store.Close(); err != nil {
+			t.Errorf("failed to roll back test transaction : %v", err)
+		}
+	})
+	return store
+}
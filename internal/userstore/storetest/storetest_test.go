@@ -0,0 +1,48 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dotenv213/umm/internal/userstore"
+)
+
+// Rolled back test stores test
+func TestTxTestStore(t *testing.T) {
+	ctx := context.Background()
+	for _, name := range []string{"first", "second"} {
+		t.Run(name, func(t *testing.T) {
+			store := TxTestStore(t)
+			if n, _ := store.Count(ctx); n != 0 {
+				t.Fatalf("Expected an empty store, got %d users", n)
+			}
+			// the same username in both sub-tests only works if the first one was rolled back
+			if err := store.Create(ctx, &userstore.User{Username: "same", Email: "same@test.com"}); err != nil {
+				t.Fatalf("Create failed : %v", err)
+			}
+			if n, _ := store.Count(ctx); n != 1 {
+				t.Errorf("Expected 1 user, got %d", n)
+			}
+		})
+	}
+}
+
+// Failed operation inside the test transaction test
+func TestTxTestStoreFailedOperation(t *testing.T) {
+	ctx := context.Background()
+	store := TxTestStore(t)
+
+	u := &userstore.User{Username: "a", Email: "a@test.com"}
+	_ = store.Create(ctx, u)
+	if err := store.Create(ctx, &userstore.User{Username: "a", Email: "b@test.com"}); err != userstore.ErrDuplicateUser {
+		t.Fatalf("Expected duplicate user, got %v", err)
+	}
+	// the failed create must not break the transaction
+	if err := store.Update(ctx, &userstore.User{ID: u.ID, Username: "renamed", Email: "a@test.com"}); err != nil {
+		t.Fatalf("Update failed : %v", err)
+	}
+	entries, _ := store.ListAudit(ctx, u.ID)
+	if len(entries) != 2 {
+		t.Errorf("Expected create and update audit entries, got %d", len(entries))
+	}
+}
@@ -0,0 +1,90 @@
+package userstore
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Username unique per tenant test
+func TestTenantUniqueness(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tenants.db")
+	one := storeAt(t, path, WithTenant(1))
+	two := storeAt(t, path, WithTenant(2))
+
+	a := &User{Username: "same", Email: "same@test.com"}
+	if err := one.Create(ctx, a); err != nil {
+		t.Fatalf("Create in tenant 1 failed : %v", err)
+	}
+	b := &User{Username: "same", Email: "same@test.com"}
+	if err := two.Create(ctx, b); err != nil {
+		t.Fatalf("Expected the same username in another tenant, got %v", err)
+	}
+	if err := one.Create(ctx, &User{Username: "same", Email: "other@test.com"}); err != ErrDuplicateUser {
+		t.Errorf("Expected duplicate user within a tenant, got %v", err)
+	}
+
+	if _, err := two.GetById(ctx, a.ID); err != ErrUserNotFound {
+		t.Errorf("Expected tenant 2 not to see tenant 1 users, got %v", err)
+	}
+	if err := two.Delete(ctx, a.ID); err != ErrUserNotFound {
+		t.Errorf("Expected delete across tenants to fail, got %v", err)
+	}
+	for _, s := range []Store{one, two} {
+		if n, _ := s.Count(ctx); n != 1 {
+			t.Errorf("Expected 1 user in tenant %d, got %d", s.Config().Tenant, n)
+		}
+	}
+}
+
+// Rebuild of users keeps related rows and ids test
+func TestTenantMigration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`
+	CREATE TABLE users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username TEXT NOT NULL UNIQUE,
+		email TEXT NOT NULL UNIQUE,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE email_changes (
+		token_hash TEXT PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		new_email TEXT NOT NULL,
+		expires_at DATETIME NOT NULL
+	);
+	INSERT INTO users (username, email) VALUES ('kept', 'kept@test.com'), ('gone', 'gone@test.com');
+	DELETE FROM users WHERE username = 'gone';`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`INSERT INTO email_changes VALUES ('hash', 1, 'new@test.com', ?)`, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	store := storeAt(t, path)
+	var pending int
+	if err := store.(*sqlStore).db.QueryRow(`SELECT COUNT(*) FROM email_changes`).Scan(&pending); err != nil {
+		t.Fatal(err)
+	}
+	if pending != 1 {
+		t.Errorf("Expected the pending email change to survive, got %d", pending)
+	}
+
+	u := &User{Username: "next", Email: "next@test.com"}
+	if err := store.Create(context.Background(), u); err != nil {
+		t.Fatalf("Create failed : %v", err)
+	}
+	if u.ID != 3 {
+		t.Errorf("Expected ids of deleted users not to be reused, got %d", u.ID)
+	}
+}
@@ -0,0 +1,138 @@
+package userstore
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+)
+
+// querier runs statements on the pool or on the transaction of BeginStore
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// conn is where the store runs its statements
+func (s *sqlStore) conn() querier {
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.db
+}
+
+// BeginStore starts a transaction on store and returns a Store
+// whose every operation runs inside it
+// changes are only seen through the returned store and are
+// rolled back by its Close, store itself stays open
+// operations that would begin a transaction use a savepoint instead
+// and OnCreate hooks run before anything is committed
+// Backup, CloneTo and Checkpoint still work outside the transaction
+// meant for tests that roll back instead of cleaning up
+func BeginStore(ctx context.Context, store Store) (Store, error) {
+	return BeginStoreTx(ctx, store, nil)
+}
+
+// BeginStoreTx is BeginStore with options for the transaction
+// sqlite transactions are always serializable so the isolation level
+// has no effect, ReadOnly makes every write inside fail
+func BeginStoreTx(ctx context.Context, store Store, opts *sql.TxOptions) (Store, error) {
+	s, err := bindable(store)
+	if err != nil {
+		return nil, err
+	}
+	tx, release, err := s.beginTx(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to begin transctions : %w", err)
+	}
+	bound := *s
+	bound.tx = tx
+	bound.release = release
+	return &bound, nil
+}
+
+// WithTx runs fn with a store bound to one transaction
+// and commits it when fn returns nil, otherwise it is rolled back
+// opts is like BeginStoreTx, nil is a default read-write transaction
+func WithTx(ctx context.Context, store Store, opts *sql.TxOptions, fn func(Store) error) error {
+	bound, err := BeginStoreTx(ctx, store, opts)
+	if err != nil {
+		return err
+	}
+	s := bound.(*sqlStore)
+	if err := fn(bound); err != nil {
+		s.Close()
+		return err
+	}
+	err = s.tx.Commit()
+	s.release()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction : %w", err)
+	}
+	return nil
+}
+
+func bindable(store Store) (*sqlStore, error) {
+	// a transaction reads and writes the primary
+	if r, ok := store.(*ReplicaStore); ok {
+		store = r.Store
+	}
+	s, ok := store.(*sqlStore)
+	if !ok {
+		return nil, errors.New("store was not opened with NewDb")
+	}
+	if s.tx != nil {
+		return nil, errors.New("store is already in a transaction")
+	}
+	return s, nil
+}
+
+// beginTx starts a transaction with opts and returns a release func
+// to call once it ended, the driver ignores opts so a read-only one
+// runs on its own connection with PRAGMA query_only until release
+func (s *sqlStore) beginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, func(), error) {
+	if opts == nil || !opts.ReadOnly {
+		tx, err := s.db.BeginTx(ctx, opts)
+		return tx, func() {}, err
+	}
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	release := func() {
+		// the connection goes back to the pool writable, or not at all
+		if _, err := conn.ExecContext(context.Background(), `PRAGMA query_only = OFF`); err != nil {
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}
+	if _, err := conn.ExecContext(ctx, `PRAGMA query_only = ON`); err != nil {
+		release()
+		return nil, nil, err
+	}
+	tx, err := conn.BeginTx(ctx, opts)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	return tx, release, nil
+}
+
+// inSavepoint runs fn inside a savepoint of the BeginStore transaction
+// so a failed operation is undone like a rolled back transaction
+func (s *sqlStore) inSavepoint(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	if _, err := s.tx.ExecContext(ctx, `SAVEPOINT store_op`); err != nil {
+		return fmt.Errorf("failed to create savepoint : %w", err)
+	}
+	if err := fn(s.tx); err != nil {
+		s.tx.ExecContext(ctx, `ROLLBACK TO store_op`)
+		s.tx.ExecContext(ctx, `RELEASE store_op`)
+		return err
+	}
+	if _, err := s.tx.ExecContext(ctx, `RELEASE store_op`); err != nil {
+		return fmt.Errorf("failed to release savepoint : %w", err)
+	}
+	return nil
+}
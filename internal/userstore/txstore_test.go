@@ -0,0 +1,66 @@
+package userstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+// Read-only WithTx test
+func TestWithTxReadOnly(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+	_ = store.Create(ctx, &User{Username: "reader", Email: "reader@test.com"})
+
+	var count int64
+	var createErr error
+	err := WithTx(ctx, store, &sql.TxOptions{ReadOnly: true}, func(tx Store) error {
+		var err error
+		if count, err = tx.Count(ctx); err != nil {
+			return err
+		}
+		createErr = tx.Create(ctx, &User{Username: "writer", Email: "writer@test.com"})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTx failed : %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 user, got %d", count)
+	}
+	if createErr == nil {
+		t.Error("Expected write in a read-only transaction to fail")
+	}
+
+	// the connection is writable again afterwards
+	if err := store.Create(ctx, &User{Username: "writer", Email: "writer@test.com"}); err != nil {
+		t.Fatalf("Create after read-only transaction failed : %v", err)
+	}
+}
+
+// WithTx commit and rollback test
+func TestWithTx(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	err := WithTx(ctx, store, nil, func(tx Store) error {
+		return tx.Create(ctx, &User{Username: "kept", Email: "kept@test.com"})
+	})
+	if err != nil {
+		t.Fatalf("WithTx failed : %v", err)
+	}
+
+	failed := errors.New("failed")
+	err = WithTx(ctx, store, nil, func(tx Store) error {
+		_ = tx.Create(ctx, &User{Username: "dropped", Email: "dropped@test.com"})
+		return failed
+	})
+	if err != failed {
+		t.Fatalf("Expected fn error, got %v", err)
+	}
+
+	if n, _ := store.Count(ctx); n != 1 {
+		t.Errorf("Expected only the committed user, got %d", n)
+	}
+}
@@ -0,0 +1,64 @@
+package userstore
+
+import (
+	"context"
+	"regexp"
+	"testing"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+// CRUD under the uuid strategy test
+func TestUUIDStrategy(t *testing.T) {
+	store := storeWithOptions(t, WithIDStrategy(UUID))
+	ctx := context.Background()
+
+	u := &User{Username: "uu", Email: "uu@test.com"}
+	if err := store.Create(ctx, u); err != nil {
+		t.Fatalf("Create failed : %v", err)
+	}
+	if !uuidPattern.MatchString(u.UUID) {
+		t.Fatalf("Expected a v4 uuid, got %q", u.UUID)
+	}
+
+	got, err := store.GetByUUID(ctx, u.UUID)
+	if err != nil {
+		t.Fatalf("GetByUUID failed : %v", err)
+	}
+	if got.ID != u.ID || got.Username != "uu" {
+		t.Errorf("Unexpected user %+v", got)
+	}
+
+	got.Email = "changed@test.com"
+	if err := store.Update(ctx, got); err != nil {
+		t.Fatalf("Update failed : %v", err)
+	}
+	updated, _ := store.GetByUUID(ctx, u.UUID)
+	if updated.Email != "changed@test.com" || updated.UUID != u.UUID {
+		t.Errorf("Expected email change with same uuid, got %+v", updated)
+	}
+
+	if err := store.Delete(ctx, got.ID); err != nil {
+		t.Fatalf("Delete failed : %v", err)
+	}
+	if _, err := store.GetByUUID(ctx, u.UUID); err != ErrUserNotFound {
+		t.Errorf("Expected user not found after delete, got %v", err)
+	}
+}
+
+// Default strategy has no uuid test
+func TestIntegerStrategyHasNoUUID(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	u1 := &User{Username: "a", Email: "a@test.com"}
+	u2 := &User{Username: "b", Email: "b@test.com"}
+	_ = store.Create(ctx, u1)
+	// NULL uuids do not collide in the unique index
+	if err := store.Create(ctx, u2); err != nil {
+		t.Fatalf("Create failed : %v", err)
+	}
+	if u1.UUID != "" {
+		t.Errorf("Expected no uuid, got %q", u1.UUID)
+	}
+}
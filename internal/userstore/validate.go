@@ -0,0 +1,57 @@
+package userstore
+
+import (
+	"net/mail"
+	"strings"
+)
+
+// emailDomain returns the lowercased part after the last @
+func emailDomain(email string) string {
+	i := strings.LastIndex(email, "@")
+	if i < 0 {
+		return ""
+	}
+	return strings.ToLower(email[i+1:])
+}
+
+// validate checks a user against the configured rules
+// before anything is written
+func (s *sqlStore) validate(user *User) error {
+	// sqlite stores a NUL fine but C strings and some exports cut at it
+	if strings.ContainsRune(user.Username, 0) || strings.ContainsRune(user.Email, 0) ||
+		(user.DisplayName != nil && strings.ContainsRune(*user.DisplayName, 0)) {
+		return ErrInvalidCharacter
+	}
+	if !s.cfg.emailOptional && strings.TrimSpace(user.Email) == "" {
+		return ErrEmailRequired
+	}
+	// an optional email may be empty but not malformed
+	if user.Email != "" {
+		if err := validateEmailFormat(user.Email); err != nil {
+			return err
+		}
+	}
+	// an empty role becomes RoleUser on create
+	if user.Role != "" && !validRoles[user.Role] {
+		return ErrInvalidRole
+	}
+	domain := emailDomain(user.Email)
+	if s.cfg.deniedDomains[domain] {
+		return ErrEmailDomainNotAllowed
+	}
+	// an empty allow-list lets every domain through
+	if len(s.cfg.allowedDomains) > 0 && !s.cfg.allowedDomains[domain] {
+		return ErrEmailDomainNotAllowed
+	}
+	return nil
+}
+
+// validateEmailFormat accepts a bare address like a@b.com
+// display names such as "A <a@b.com>" are rejected
+func validateEmailFormat(email string) error {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return ErrInvalidEmail
+	}
+	return nil
+}
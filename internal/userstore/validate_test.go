@@ -0,0 +1,85 @@
+package userstore
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Allowed domain test
+func TestAllowedEmailDomain(t *testing.T) {
+	store := storeWithOptions(t, WithAllowedEmailDomains("company.com"))
+	ctx := context.Background()
+
+	if err := store.Create(ctx, &User{Username: "a", Email: "a@Company.COM"}); err != nil {
+		t.Fatalf("Expected allowed domain to pass, got %v", err)
+	}
+}
+
+// Domain missing from allow-list test
+func TestDomainNotInAllowList(t *testing.T) {
+	store := storeWithOptions(t, WithAllowedEmailDomains("company.com"))
+	ctx := context.Background()
+
+	err := store.Create(ctx, &User{Username: "a", Email: "a@gmail.com"})
+	if err != ErrEmailDomainNotAllowed {
+		t.Fatalf("Expected domain not allowed error, got %v", err)
+	}
+
+	u := &User{Username: "b", Email: "b@company.com"}
+	_ = store.Create(ctx, u)
+	u.Email = "b@gmail.com"
+	if err := store.Update(ctx, u); err != ErrEmailDomainNotAllowed {
+		t.Fatalf("Expected domain not allowed error on update, got %v", err)
+	}
+}
+
+// Denied domain test
+func TestDeniedEmailDomain(t *testing.T) {
+	store := storeWithOptions(t, WithDeniedEmailDomains("spam.io"))
+	ctx := context.Background()
+
+	err := store.Create(ctx, &User{Username: "a", Email: "a@SPAM.io"})
+	if err != ErrEmailDomainNotAllowed {
+		t.Fatalf("Expected domain not allowed error, got %v", err)
+	}
+	if err := store.Create(ctx, &User{Username: "b", Email: "b@ok.io"}); err != nil {
+		t.Fatalf("Expected other domains to pass, got %v", err)
+	}
+}
+
+// Null byte test
+func TestNullByteRejected(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	if err := store.Create(ctx, &User{Username: "al\x00ice", Email: "alice@test.com"}); err != ErrInvalidCharacter {
+		t.Errorf("Expected ErrInvalidCharacter for username, got %v", err)
+	}
+	if err := store.Create(ctx, &User{Username: "alice", Email: "al\x00ice@test.com"}); err != ErrInvalidCharacter {
+		t.Errorf("Expected ErrInvalidCharacter for email, got %v", err)
+	}
+	if err := store.Create(ctx, &User{Username: "alice", Email: "alice@test.com"}); err != nil {
+		t.Errorf("Expected clean values to be accepted, got %v", err)
+	}
+}
+
+// Email format test
+func TestEmailFormatRejected(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+
+	for _, email := range []string{"not-an-email", "Alice <alice@test.com>"} {
+		if err := store.Create(ctx, &User{Username: "alice", Email: email}); err != ErrInvalidEmail {
+			t.Errorf("Expected ErrInvalidEmail for %q, got %v", email, err)
+		}
+	}
+	_, _, err := store.ImportCSV(ctx, strings.NewReader("username,email\nbad,not-an-email\n"), false)
+	if !errors.Is(err, ErrInvalidEmail) {
+		t.Errorf("Expected ImportCSV to reject a malformed email, got %v", err)
+	}
+	if n, _ := store.Count(ctx); n != 0 {
+		t.Errorf("Expected no users, got %d", n)
+	}
+}
@@ -0,0 +1,64 @@
+package userstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Verify runs PRAGMA integrity_check and PRAGMA foreign_key_check
+// and returns ErrCorruptDatabase listing every problem found
+func (s *sqlStore) Verify(ctx context.Context) error {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	return verifyDB(ctx, s.db)
+}
+
+func verifyDB(ctx context.Context, db *sql.DB) error {
+	var problems []string
+
+	rows, err := db.QueryContext(ctx, `PRAGMA integrity_check`)
+	if err != nil {
+		return fmt.Errorf("%w : %v", ErrCorruptDatabase, err)
+	}
+	// a healthy database reports a single "ok" row
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			rows.Close()
+			return fmt.Errorf("%w : %v", ErrCorruptDatabase, err)
+		}
+		if msg != "ok" {
+			problems = append(problems, msg)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%w : %v", ErrCorruptDatabase, err)
+	}
+
+	// every row here is a reference to a missing parent
+	rows, err = db.QueryContext(ctx, `PRAGMA foreign_key_check`)
+	if err != nil {
+		return fmt.Errorf("failed to check foreign keys : %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table, parent string
+		var rowid sql.NullInt64
+		var fkid int
+		if err := rows.Scan(&table, &rowid, &parent, &fkid); err != nil {
+			return fmt.Errorf("failed to scan foreign key check : %w", err)
+		}
+		problems = append(problems, fmt.Sprintf("%s row %d references missing %s", table, rowid.Int64, parent))
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error during rows iteration : %w", err)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w : %s", ErrCorruptDatabase, strings.Join(problems, "; "))
+	}
+	return nil
+}
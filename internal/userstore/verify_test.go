@@ -0,0 +1,17 @@
+package userstore
+
+import (
+	"context"
+	"testing"
+)
+
+// Fresh store verify test
+func TestVerifyFreshStore(t *testing.T) {
+	store := StoreTest(t)
+	ctx := context.Background()
+	_ = store.Create(ctx, &User{Username: "v", Email: "v@test.com"})
+
+	if err := store.Verify(ctx); err != nil {
+		t.Fatalf("Expected fresh store to verify, got %v", err)
+	}
+}